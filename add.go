@@ -2,28 +2,39 @@ package ldapx
 
 import (
 	"github.com/go-ldap/ldap/v3"
-	"strings"
 )
 
 // AddAttributeValuesIgnoreCase adds the given values to the attribute.
 func (e *Entry) AddAttributeValuesIgnoreCase(attr string, value []string, ignoreCase bool) {
+	// A bound entry drops values that match each other
+	e.addAttributeValues(attr, value, e.valueNormalizer(attr, ignoreCase), e.schema != nil && !ignoreCase)
+}
+
+// addAttributeValues adds the values that match no value of the attribute
+// under normalize. If unique is true, values of a new attribute that match
+// an earlier value are dropped as well.
+func (e *Entry) addAttributeValues(attr string, value []string, normalize normalizer, unique bool) {
 	if len(value) == 0 {
 		return
 	}
 
 	a := e.Attributes.Get(attr)
 	if a == nil {
+		if unique {
+			value = uniqueValues(value, normalize)
+		}
 		e.Attributes.PutEntryAttribute(ldap.NewEntryAttribute(attr, value))
 		e.AddAttributeChange("add", attr, value)
 		return
 	}
 
+	values := append([]string(nil), a.Values...)
 	var addedValues []string //nolint:prealloc
 
 	for _, o := range value {
 		var found bool
-		for _, d := range a.Values {
-			if (o == d) || (ignoreCase && strings.EqualFold(o, d)) {
+		for _, d := range values {
+			if (o == d) || normalize(o) == normalize(d) {
 				found = true
 				break
 			}
@@ -36,11 +47,14 @@ func (e *Entry) AddAttributeValuesIgnoreCase(attr string, value []string, ignore
 
 		// If the value was not found, add it to the added values
 		addedValues = append(addedValues, o)
-		a.Values = append(a.Values, o)
+		values = append(values, o)
 	}
 
-	// Add the attribute change to the entry
+	// Add the attribute change to the entry. The attribute is replaced rather
+	// than appended to, so that its byte values stay in step and other
+	// references to it are not changed.
 	if len(addedValues) > 0 {
+		e.Attributes.PutEntryAttribute(ldap.NewEntryAttribute(a.Name, values))
 		e.AddAttributeChange("add", attr, addedValues)
 	}
 }
@@ -59,3 +73,18 @@ func (e *Entry) AddAttributeValueIgnoreCase(attr string, value string, ignoreCas
 func (e *Entry) AddAttributeValue(attr string, value string) {
 	e.AddAttributeValueIgnoreCase(attr, value, false)
 }
+
+// uniqueValues returns the values without those that match an earlier value.
+func uniqueValues(values []string, normalize normalizer) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		n := normalize(v)
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		unique = append(unique, v)
+	}
+	return unique
+}
@@ -59,11 +59,9 @@ func (m AttributeMap) Rename(from, to string) {
 		return
 	}
 
-	// Rename the attribute in the value
-	v.Name = to
-
-	// Add "new" version
-	m.PutEntryAttribute(v)
+	// Add "new" version. The attribute is copied rather than renamed in
+	// place, as the original attributes of an entry may share it.
+	m.PutEntryAttribute(ldap.NewEntryAttribute(to, v.Values))
 
 	// And delete old attribute
 	m.Delete(from)
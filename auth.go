@@ -0,0 +1,91 @@
+package ldapx
+
+import (
+	"crypto/tls"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Authenticator authenticates a connection to the LDAP server.
+type Authenticator interface {
+	Bind(conn *ldap.Conn) error
+}
+
+// AuthenticatorFunc adapts a function to an Authenticator. It is the hook for
+// SASL mechanisms not provided here, such as DIGEST-MD5, GSSAPI or NTLM.
+type AuthenticatorFunc func(conn *ldap.Conn) error
+
+// Bind calls f(conn).
+func (f AuthenticatorFunc) Bind(conn *ldap.Conn) error {
+	return f(conn)
+}
+
+// SimpleAuth performs a simple bind. An empty password results in an
+// unauthenticated bind.
+type SimpleAuth struct {
+	DN       string // DN to bind as
+	Password string // Password of the DN
+}
+
+// Bind performs the simple bind.
+func (a SimpleAuth) Bind(conn *ldap.Conn) error {
+	return bind(conn, a.DN, a.Password)
+}
+
+// ExternalAuth performs a SASL EXTERNAL bind. The server derives the identity
+// from the TLS client certificate, or from the peer credentials on ldapi://.
+type ExternalAuth struct{}
+
+// Bind performs the SASL EXTERNAL bind.
+func (ExternalAuth) Bind(conn *ldap.Conn) error {
+	return conn.ExternalBind()
+}
+
+var (
+	_ Authenticator = SimpleAuth{}
+	_ Authenticator = ExternalAuth{}
+	_ Authenticator = AuthenticatorFunc(nil)
+)
+
+// WithAuth sets the authenticator used to bind pooled connections.
+func WithAuth(auth Authenticator) Option {
+	return func(c *config) {
+		c.auth = auth
+	}
+}
+
+// WithClientCertificate adds a client certificate to the TLS configuration,
+// for use with mutual TLS and ExternalAuth.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *config) {
+		c.clientCerts = append(c.clientCerts, cert)
+	}
+}
+
+// clientTLSConfig returns the TLS configuration with the client certificates added.
+func clientTLSConfig(tlsConfig *tls.Config, certs []tls.Certificate) *tls.Config {
+	if len(certs) == 0 {
+		return tlsConfig
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{} //nolint: gosec
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+	tlsConfig.Certificates = append(tlsConfig.Certificates, certs...)
+	return tlsConfig
+}
+
+// authenticate binds the connection with the given authenticator, refusing
+// to do so over cleartext when StartTLS is required.
+func (c *Conn) authenticate(conn *ldap.Conn, url string, auth Authenticator) error {
+	var dn string
+	if a, ok := auth.(SimpleAuth); ok {
+		dn = a.DN
+	}
+
+	if err := checkSecure(conn, url, c.startTLS); err != nil {
+		return wrapError("bind", dn, err)
+	}
+	return wrapError("bind", dn, auth.Bind(conn))
+}
@@ -0,0 +1,87 @@
+package ldapx
+
+import (
+	"errors"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_ExternalAuthWithClientCertificate(t *testing.T) {
+	s := newFakeServer(t)
+	tlsConfig := s.enableTLS()
+
+	conn, err := Open(s.url(),
+		WithTLSConfig(tlsConfig),
+		WithStartTLS(StartTLSRequired),
+		WithClientCertificate(s.clientCertificate("service")),
+		WithAuth(ExternalAuth{}),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, []string{"sasl:EXTERNAL:service"}, s.bindIdentities())
+}
+
+func TestConn_ExecuteAsAuthRebinds(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithBind("cn=service", "secret"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var called bool
+	custom := AuthenticatorFunc(func(lc *ldap.Conn) error {
+		called = true
+		return lc.Bind("cn=other", "password")
+	})
+	_, err = conn.ExecuteAsAuth(custom, func(*ldap.Conn) (interface{}, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	assert.True(t, called)
+	assert.Equal(t, []string{"simple:cn=service", "simple:cn=other", "simple:cn=service"}, s.bindIdentities())
+}
+
+func TestConn_CheckAuthFailure(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	failed := errors.New("mechanism not supported")
+	err = conn.CheckAuth(AuthenticatorFunc(func(*ldap.Conn) error { return failed }))
+	assert.True(t, errors.Is(err, failed))
+}
+
+func TestConn_ExecuteAsAuthRebindFailure(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithBind("cn=service", "secret"), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The server rejects the bind back to the pool identity.
+	s.setOverride(func(op ber.Tag, dn string) uint16 {
+		if op == ldap.ApplicationBindRequest && dn == "cn=service" {
+			return ldap.LDAPResultInvalidCredentials
+		}
+		return ldap.LDAPResultSuccess
+	})
+	_, err = conn.ExecuteAs("cn=other", "password", func(*ldap.Conn) (interface{}, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+	s.setOverride(nil)
+
+	// The connection bound as cn=other is not reused.
+	_, err = conn.RootDSE()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, s.dials.Load())
+	assert.Equal(t, []string{"simple:cn=service", "simple:cn=other", "simple:cn=service", "simple:cn=service"}, s.bindIdentities())
+}
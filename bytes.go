@@ -0,0 +1,68 @@
+package ldapx
+
+// The byte mutators manage binary attributes such as jpegPhoto,
+// userCertificate;binary, objectGUID and objectSid. Values are compared byte
+// for byte, whatever the schema or case. They are stored like string values,
+// which hold any bytes, so the two kinds of mutator may be mixed and Values
+// and ByteValues of an attribute stay consistent.
+
+// bytesToStrings converts byte values to the strings that hold them.
+func bytesToStrings(values [][]byte) []string {
+	if values == nil {
+		return nil
+	}
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return s
+}
+
+// AddAttributeBytes adds the given values to the attribute.
+func (e *Entry) AddAttributeBytes(attr string, values [][]byte) {
+	e.addAttributeValues(attr, bytesToStrings(values), exactValue, false)
+}
+
+// ReplaceAttributeBytes removes all values from the attribute and adds the
+// given values.
+func (e *Entry) ReplaceAttributeBytes(attr string, values [][]byte) {
+	e.replaceAttributeValues(attr, bytesToStrings(values), exactValue)
+}
+
+// DeleteAttributeBytes deletes the given values from the attribute.
+func (e *Entry) DeleteAttributeBytes(attr string, values [][]byte) {
+	e.deleteAttributeValues(attr, bytesToStrings(values), exactValue)
+}
+
+// SyncAttributeBytes adds and removes values of the attribute to match the
+// values provided.
+func (e *Entry) SyncAttributeBytes(attr string, values [][]byte) {
+	value := bytesToStrings(values)
+	currentValues := e.GetAttributeValues(attr)
+
+	e.addAttributeValues(attr, valuesNotIn(value, currentValues, exactValue), exactValue, false)
+	e.deleteAttributeValues(attr, valuesNotIn(currentValues, value, exactValue), exactValue)
+}
+
+// GetAttributeBytes returns the values of the attribute as byte slices.
+func (e *Entry) GetAttributeBytes(attr string) [][]byte {
+	a := e.Attributes.Get(attr)
+	if a == nil {
+		return nil
+	}
+	values := make([][]byte, len(a.Values))
+	for i, v := range a.Values {
+		values[i] = []byte(v)
+	}
+	return values
+}
+
+// GetAttributeByteValue returns the first value of the attribute as a byte
+// slice, or nil if the attribute has no values.
+func (e *Entry) GetAttributeByteValue(attr string) []byte {
+	a := e.Attributes.Get(attr)
+	if a == nil || len(a.Values) == 0 {
+		return nil
+	}
+	return []byte(a.Values[0])
+}
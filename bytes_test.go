@@ -0,0 +1,128 @@
+package ldapx
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertConsistent checks that Values and ByteValues of the attribute hold
+// the same values.
+func assertConsistent(t *testing.T, e *Entry, attr string) {
+	t.Helper()
+
+	a := e.Attributes.Get(attr)
+	require.NotNil(t, a)
+	require.Len(t, a.ByteValues, len(a.Values))
+	for i, v := range a.Values {
+		assert.Equal(t, []byte(v), a.ByteValues[i])
+	}
+}
+
+var (
+	guid1 = []byte{0x00, 0xff, 0x41, 0x80}
+	guid2 = []byte{0x00, 0xff, 0x61, 0x80}
+)
+
+func TestEntry_AttributeBytes(t *testing.T) {
+	e := NewEntryFromLdapEntry(&ldap.Entry{DN: "cn=test", Attributes: []*ldap.EntryAttribute{
+		{Name: "objectGUID", ByteValues: [][]byte{guid1}},
+	}})
+	assertConsistent(t, e, "objectGUID")
+	assert.Equal(t, [][]byte{guid1}, e.GetAttributeBytes("objectguid"))
+	assert.Equal(t, guid1, e.GetAttributeByteValue("objectGUID"))
+
+	// Values that differ only in case are different bytes.
+	e.AddAttributeBytes("objectGUID", [][]byte{guid1, guid2})
+	assertConsistent(t, e, "objectGUID")
+	assert.Equal(t, [][]byte{guid1, guid2}, e.GetAttributeBytes("objectGUID"))
+	assert.Equal(t, []AttributeChange{{Action: "add", Attr: "objectGUID", Value: []string{string(guid2)}}}, e.Changes)
+
+	e.DeleteAttributeBytes("objectGUID", [][]byte{guid1})
+	assertConsistent(t, e, "objectGUID")
+	assert.Equal(t, [][]byte{guid2}, e.GetAttributeBytes("objectGUID"))
+
+	e.ReplaceAttributeBytes("objectGUID", [][]byte{guid2})
+	assert.Len(t, e.Changes, 2)
+	e.ReplaceAttributeBytes("objectGUID", [][]byte{guid1})
+	assertConsistent(t, e, "objectGUID")
+	assert.Len(t, e.Changes, 3)
+
+	assert.Nil(t, e.GetAttributeBytes("missing"))
+	assert.Nil(t, e.GetAttributeByteValue("missing"))
+}
+
+func TestEntry_SyncAttributeBytes(t *testing.T) {
+	e := NewEntryFromLdapEntry(ldap.NewEntry("cn=test", map[string][]string{
+		"userCertificate;binary": {string(guid1)},
+	}))
+	e.BindSchema(loadSchemaDump(t, "openldap.ldif"))
+
+	e.SyncAttributeBytes("userCertificate;binary", [][]byte{guid1})
+	assert.False(t, e.Changed())
+
+	e.SyncAttributeBytes("userCertificate;binary", [][]byte{guid2})
+	assertConsistent(t, e, "userCertificate;binary")
+	assert.Equal(t, [][]byte{guid2}, e.GetAttributeBytes("userCertificate;binary"))
+	assert.Equal(t, []AttributeChange{
+		{Action: "add", Attr: "userCertificate;binary", Value: []string{string(guid2)}},
+		{Action: "delete", Attr: "userCertificate;binary", Value: []string{string(guid1)}},
+	}, e.Changes)
+}
+
+func TestEntry_AddAttributeValuesKeepsBytes(t *testing.T) {
+	e := NewEntryFromLdapEntry(ldap.NewEntry("cn=test", map[string][]string{"mail": {"a@example.com"}}))
+	e.AddAttributeValue("mail", "b@example.com")
+	assertConsistent(t, e, "mail")
+
+	// The original state is not changed by later changes.
+	assert.Equal(t, []string{"a@example.com"}, e.originalAttributes.Get("mail").Values)
+}
+
+func TestEntry_UpdateSendsBytes(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=test,dc=example,dc=com", map[string][]string{"objectClass": {"person"}, "cn": {"test"}})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	photo := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10}
+	err = conn.UpdateEntry("cn=test,dc=example,dc=com", func(e *Entry) (*Entry, error) {
+		e.AddAttributeBytes("jpegPhoto", [][]byte{photo})
+		return e, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{string(photo)}, s.get("cn=test,dc=example,dc=com")["jpegPhoto"])
+
+	entry, err := conn.Lookup("cn=test,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.Equal(t, photo, entry.GetAttributeByteValue("jpegPhoto"))
+}
+
+func TestEntry_UpdateSendsRawBytes(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	photo := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x80}
+	e := NewEntry("cn=photo,dc=example,dc=com")
+	e.AddAttributeValue("objectClass", "person")
+	e.AddAttributeValue("cn", "photo")
+	e.AddAttributeBytes("jpegPhoto", [][]byte{photo})
+	require.NoError(t, e.Update(conn))
+	assert.Equal(t, []string{string(photo)}, s.get("cn=photo,dc=example,dc=com")["jpegPhoto"])
+
+	e, err = conn.Lookup("cn=photo,dc=example,dc=com")
+	require.NoError(t, err)
+	e.AddAttributeBytes("objectGUID", [][]byte{guid1})
+	e.ReplaceAttributeBytes("jpegPhoto", [][]byte{guid2})
+	require.NoError(t, e.Update(conn))
+	stored := s.get("cn=photo,dc=example,dc=com")
+	assert.Equal(t, []string{string(guid1)}, stored["objectGUID"])
+	assert.Equal(t, []string{string(guid2)}, stored["jpegPhoto"])
+}
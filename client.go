@@ -3,25 +3,36 @@
 package ldapx
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/go-ldap/ldap/v3"
 	"github.com/jbirdman/ldapurl"
-	"github.com/silenceper/pool"
+	"iter"
+	"net"
 	"net/url"
 	"time"
 )
 
 // Conn represents a connection to an LDAP server.
 type Conn struct {
-	ldapURL      *ldapurl.LdapURL // LDAP URL
-	url          string
-	pool         pool.Pool //
-	bindDN       string
-	bindPassword string
-	schema       *LDAPSchema
-	tlsConfig    *tls.Config
-	txConn       *ldap.Conn
+	ldapURL        *ldapurl.LdapURL // LDAP URL
+	url            string
+	servers        *serverSet    // Servers to connect to
+	pool           *connPool     // Pool of bound connections
+	readServers    *serverSet    // Servers to read from, nil if reads use pool
+	readPool       *connPool     // Pool of bound connections for reads
+	sticky         *stickyDNs    // Recently written DNs
+	retry          RetryPolicy   // How failed operations are retried
+	auth           Authenticator // Authenticator used to bind pooled connections
+	schema         *LDAPSchema
+	validate       bool // Validate entries against the schema in Update
+	schemaMatching bool // Bind looked up entries to the schema
+	tlsConfig      *tls.Config
+	startTLS       StartTLSMode
+	dialTimeout    time.Duration // Maximum time to connect to a server
+	txConn         *ldap.Conn
 }
 
 // Client represents a client that can execute LDAP operations.
@@ -32,11 +43,14 @@ type Client interface {
 	Del(*ldap.DelRequest) error
 	CheckBind(dn string, password string) error
 	Modify(*ldap.ModifyRequest) error
+	ModifyDN(*ldap.ModifyDNRequest) error
 	Compare(dn string, attribute string, value string) (bool, error)
 	PasswordModify(*ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error)
 	Search(*ldap.SearchRequest) (*ldap.SearchResult, error)
 	SearchWithPaging(request *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
-	Lookup(dn string) (*Entry, error)
+	SearchIter(request *ldap.SearchRequest, pageSize uint32, opts ...SearchIterOption) iter.Seq2[*Entry, error]
+	Lookup(dn string, opts ...LookupOption) (*Entry, error)
+	Exists(dn string) (bool, error)
 	LookupOrNew(dn string) (*Entry, error)
 	QuickSearch(dn string, filter string, attributes []string) (*ldap.SearchResult, error)
 	FindEntry(dn string, filter string, attributes []string) (*Entry, error)
@@ -47,60 +61,119 @@ type Client interface {
 	Close() error
 }
 
-var _ Client = &Conn{}
+// ClientContext represents a client that can execute LDAP operations bounded
+// by a context.
+type ClientContext interface {
+	ExecuteContext(ctx context.Context, f func(*Conn) (interface{}, error)) (interface{}, error)
+	ExecuteAsContext(ctx context.Context, dn string, password string, f func(*ldap.Conn) (interface{}, error)) (interface{}, error)
+	AddContext(ctx context.Context, request *ldap.AddRequest) error
+	DelContext(ctx context.Context, request *ldap.DelRequest) error
+	CheckBindContext(ctx context.Context, dn string, password string) error
+	ModifyContext(ctx context.Context, request *ldap.ModifyRequest) error
+	ModifyDNContext(ctx context.Context, request *ldap.ModifyDNRequest) error
+	CompareContext(ctx context.Context, dn string, attribute string, value string) (bool, error)
+	PasswordModifyContext(ctx context.Context, request *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error)
+	SearchContext(ctx context.Context, request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	SearchWithPagingContext(ctx context.Context, request *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
+	SearchIterContext(ctx context.Context, request *ldap.SearchRequest, pageSize uint32, opts ...SearchIterOption) iter.Seq2[*Entry, error]
+	LookupContext(ctx context.Context, dn string, opts ...LookupOption) (*Entry, error)
+	ExistsContext(ctx context.Context, dn string) (bool, error)
+	LookupOrNewContext(ctx context.Context, dn string) (*Entry, error)
+	QuickSearchContext(ctx context.Context, dn string, filter string, attributes []string) (*ldap.SearchResult, error)
+	FindEntryContext(ctx context.Context, dn string, filter string, attributes []string) (*Entry, error)
+	RootDSEContext(ctx context.Context) (*RootDSE, error)
+	SchemaContext(ctx context.Context) (*LDAPSchema, error)
+	UpdateEntryContext(ctx context.Context, dn string, f EntryUpdateFunc) error
+	UpdateContext(ctx context.Context, entry *Entry) error
+	Close() error
+}
+
+var (
+	_ Client        = &Conn{}
+	_ ClientContext = &Conn{}
+)
 
 // OpenURLSimple opens a connection to an LDAP server using the provided URL.
+// Certificates are verified against the host name of each server dialed.
 func OpenURLSimple(ldapURL, binddn, bindpw string, insecureSkipVerify bool) (*Conn, error) {
-	// Get the host portion of the URL.
-	host, err := urlHost(ldapURL)
-	if err != nil {
-		return nil, err
-	}
-
-	// If the host is an IP address, we need to disable hostname verification.
 	return OpenURL(ldapURL, binddn, bindpw, &tls.Config{
-		ServerName:         host,
 		InsecureSkipVerify: insecureSkipVerify, //nolint: gosec
 	})
 }
 
-// urlHost returns the host portion of a URL.
-func urlHost(s string) (string, error) {
-	u, err := url.Parse(s)
-	if err != nil {
-		return "", err
-	}
-
-	return u.Host, nil
-}
-
 // OpenURL opens a connection to an LDAP server using the provided URL.
 func OpenURL(url string, bindDN string, bindPassword string, tlsConfig *tls.Config) (*Conn, error) {
-	// Parse the URL
-	ldapURL, err := ldapurl.Parse(url)
+	return Open(url, WithBind(bindDN, bindPassword), WithTLSConfig(tlsConfig))
+}
+
+// Open opens a connection to an LDAP server using the provided URL and options.
+// The URL may be a space-separated list of LDAP URLs, in which case
+// connections are spread over the servers according to the server policy.
+func Open(url string, opts ...Option) (*Conn, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// Parse the URLs
+	urls := append(splitURLs(url), cfg.servers...)
+	for _, u := range urls {
+		if _, err := parseURL(u); err != nil {
+			return nil, err
+		}
+	}
+	servers, err := newServerSet(urls, cfg.serverPolicy)
 	if err != nil {
 		return nil, err
 	}
+	ldapURL, _ := parseURL(urls[0])
+
+	// Create the connection.
+	conn := &Conn{
+		ldapURL:        ldapURL,
+		url:            url,
+		servers:        servers,
+		auth:           cfg.auth,
+		tlsConfig:      clientTLSConfig(cfg.tlsConfig, cfg.clientCerts),
+		startTLS:       cfg.startTLS,
+		dialTimeout:    cfg.dialTimeout,
+		sticky:         newStickyDNs(cfg.readYourWrites),
+		retry:          cfg.retry,
+		validate:       cfg.validate,
+		schemaMatching: cfg.schemaMatching,
+	}
 
 	// Set up the connection pool.
-	pl, err := setupConnectionPool(url, bindDN, bindPassword, tlsConfig)
+	pl, err := setupConnectionPool(conn, servers, cfg.pool)
 	if err != nil {
 		return nil, err
 	}
+	conn.pool = pl
 
-	// Create the connection.
-	conn := &Conn{
-		ldapURL:      ldapURL,
-		url:          url,
-		pool:         pl,
-		bindDN:       bindDN,
-		bindPassword: bindPassword,
-		tlsConfig:    tlsConfig,
+	// Probe servers that are marked down.
+	go servers.probe(cfg.probeInterval, conn.dial)
+
+	// Set up the read pool.
+	if len(cfg.readServers) > 0 {
+		for _, u := range cfg.readServers {
+			if _, err := parseURL(u); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+		}
+		conn.readServers, _ = newServerSet(cfg.readServers, cfg.serverPolicy)
+		conn.readPool, err = setupConnectionPool(conn, conn.readServers, cfg.pool)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		go conn.readServers.probe(cfg.probeInterval, conn.dial)
 	}
 
 	// Get the schema.
 	schema, err := conn.Schema()
 	if err != nil {
+		_ = conn.Close()
 		return nil, err
 	}
 
@@ -110,7 +183,12 @@ func OpenURL(url string, bindDN string, bindPassword string, tlsConfig *tls.Conf
 }
 
 func (c *Conn) Close() error {
-	c.pool.Release()
+	c.servers.close()
+	c.pool.release()
+	if c.readPool != nil {
+		c.readServers.close()
+		c.readPool.release()
+	}
 	return nil
 }
 
@@ -121,42 +199,50 @@ func bind(conn *ldap.Conn, bindDN string, bindPassword string) error {
 	return conn.Bind(bindDN, bindPassword)
 }
 
-// setupConnectionPool sets up the connection pool.
-func setupConnectionPool(url string, bindDN string, bindPassword string, tlsConfig *tls.Config) (pool.Pool, error) {
-	//
-	pl, err := pool.NewChannelPool(&pool.Config{
-		InitialCap:  0,
-		MaxIdle:     1,
-		MaxCap:      10,
-		IdleTimeout: 60 * time.Second,
-		Factory: func() (interface{}, error) {
-			// Dial the LDAP server.
-			conn, err := dialURL(url, tlsConfig)
-			if err != nil {
-				return nil, fmt.Errorf("create client connection error: %w", err)
-			}
-
-			// Bind to the LDAP server.
-			err = bind(conn, bindDN, bindPassword)
-			if err != nil {
-				conn.Close()
-				return nil, fmt.Errorf("create client connection bind error: %w", err)
-			}
-
-			return conn, nil
-		},
-		Close: func(conn interface{}) error {
-			return conn.(*ldap.Conn).Close()
-		},
-		Ping: func(conn interface{}) error {
-			return pingConnection(conn.(*ldap.Conn))
-		},
-	})
+// parseURL parses the LDAP URL. ldapi:// URLs are not understood by
+// ldapurl, so only their scheme and socket path are kept.
+func parseURL(rawURL string) (*ldapurl.LdapURL, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, err
 	}
+	if u.Scheme == "ldapi" {
+		return &ldapurl.LdapURL{Scheme: u.Scheme, Host: u.Path}, nil
+	}
+	return ldapurl.Parse(rawURL)
+}
 
-	return pl, nil
+// setupConnectionPool sets up a connection pool to the given servers.
+func setupConnectionPool(c *Conn, servers *serverSet, cfg poolConfig) (*connPool, error) {
+	return newConnPool(cfg, func() (*ldap.Conn, *server, error) {
+		// Dial the LDAP server.
+		conn, srv, err := servers.dial(c.dial)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create client connection error: %w", err)
+		}
+
+		// Bind to the LDAP server.
+		err = c.authenticate(conn, srv.url, c.auth)
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("create client connection bind error: %w", err)
+		}
+
+		return conn, srv, nil
+	})
+}
+
+// dial dials the given server, negotiating StartTLS as configured.
+func (c *Conn) dial(url string) (*ldap.Conn, error) {
+	return dialStartTLS(url, c.tlsConfig, c.startTLS, c.dialTimeout)
+}
+
+// serverURL returns the URL of the server a pooled connection was dialed to.
+func (c *Conn) serverURL(lc *ldap.Conn) string {
+	if srv := c.poolOf(lc).server(lc); srv != nil {
+		return srv.url
+	}
+	return c.url
 }
 
 func pingConnection(conn *ldap.Conn) error {
@@ -164,32 +250,28 @@ func pingConnection(conn *ldap.Conn) error {
 	return err
 }
 
-// dialURL dials the LDAP server.
-func dialURL(url string, tlsConfig *tls.Config) (*ldap.Conn, error) {
-	return ldap.DialURL(url, ldap.DialWithTLSConfig(tlsConfig))
+// dialURL dials the LDAP server, giving up after timeout if it is not zero.
+func dialURL(url string, tlsConfig *tls.Config, timeout time.Duration) (*ldap.Conn, error) {
+	return ldap.DialURL(url, ldap.DialWithTLSConfig(tlsConfig), ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
 }
 
 // getConn gets a connection from the pool.
-func getConn(pool pool.Pool) (*ldap.Conn, error) {
-	lc, err := pool.Get()
-	if err != nil {
-		return nil, err
-	}
-
-	return lc.(*ldap.Conn), err
+func getConn(ctx context.Context, pool *connPool) (*ldap.Conn, error) {
+	return pool.get(ctx)
 }
 
 // putConn puts a connection back into the pool.
-func putConn(pool pool.Pool, lc *ldap.Conn) {
-	pool.Put(lc)
+func putConn(pool *connPool, lc *ldap.Conn) {
+	pool.put(lc)
 }
 
 // get gets a connection from the pool.
-func (c *Conn) get() (*ldap.Conn, error) {
+func (c *Conn) get(ctx context.Context) (*ldap.Conn, error) {
 	if c.txConn != nil {
 		return c.txConn, nil
 	}
-	return getConn(c.pool)
+	lc, err := getConn(ctx, c.pool)
+	return lc, wrapError("connect", "", err)
 }
 
 // put puts a connection back into the pool.
@@ -197,57 +279,193 @@ func (c *Conn) put(lc *ldap.Conn) {
 	if c.txConn != nil && c.txConn == lc {
 		return
 	}
-	putConn(c.pool, lc)
+	putConn(c.poolOf(lc), lc)
+}
+
+// discard closes a connection instead of returning it to the pool. A
+// transaction connection is only closed; the pool drops it when it is put back.
+func (c *Conn) discard(lc *ldap.Conn) {
+	if c.txConn != nil && c.txConn == lc {
+		_ = lc.Close()
+		return
+	}
+	c.poolOf(lc).discard(lc)
+}
+
+// release returns the connection to its pool, or drops it if it is broken.
+func (c *Conn) release(conn *ldap.Conn, broken bool) {
+	if broken {
+		c.discard(conn)
+	} else {
+		c.put(conn)
+	}
 }
 
 func (c *Conn) NewTx(conn *ldap.Conn) *Conn {
 	return &Conn{
-		txConn: conn,
+		ldapURL:        c.ldapURL,
+		url:            c.url,
+		servers:        c.servers,
+		pool:           c.pool,
+		readServers:    c.readServers,
+		readPool:       c.readPool,
+		sticky:         c.sticky,
+		retry:          c.retry,
+		auth:           c.auth,
+		schema:         c.schema,
+		validate:       c.validate,
+		schemaMatching: c.schemaMatching,
+		tlsConfig:      c.tlsConfig,
+		startTLS:       c.startTLS,
+		dialTimeout:    c.dialTimeout,
+		txConn:         conn,
 	}
 }
 
 func (c *Conn) Execute(f func(*Conn) (interface{}, error)) (interface{}, error) {
-	conn, err := c.get()
-	if err != nil {
-		return nil, err
-	}
-	defer c.put(conn)
+	return c.ExecuteContext(context.Background(), f)
+}
 
-	return f(c.NewTx(conn))
+// ExecuteContext executes a function with a connection from the pool. The
+// connection is dropped if the context is done before the function returns.
+func (c *Conn) ExecuteContext(ctx context.Context, f func(*Conn) (interface{}, error)) (interface{}, error) {
+	return c.ExecuteLdapContext(ctx, func(conn *ldap.Conn) (interface{}, error) {
+		return f(c.NewTx(conn))
+	})
 }
 
+// ExecuteLdap executes a function with a connection from the pool. See
+// ExecuteLdapContext.
 func (c *Conn) ExecuteLdap(f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
-	conn, err := c.get()
+	return c.ExecuteLdapContext(context.Background(), f)
+}
+
+// ExecuteLdapContext executes a function with a connection from the pool. The
+// connection is dropped if the context is done before the function returns.
+// The connection is always to the write servers, and the function is not
+// retried: ldapx cannot tell whether it only reads or whether it is safe to
+// run twice. Use the operation methods to have reads split and retried.
+// Errors are returned as OpErrors, like those of the operation methods.
+func (c *Conn) ExecuteLdapContext(ctx context.Context, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	result, err := c.execute(ctx, f)
+	return result, wrapError("execute", "", err)
+}
+
+// execute executes a function with a connection from the pool.
+func (c *Conn) execute(ctx context.Context, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	conn, err := c.get(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer c.put(conn)
+	return c.run(ctx, conn, f)
+}
 
-	return f(conn)
+// run runs f with the connection and then returns the connection to its pool,
+// or drops it if the context was done first or the connection broke.
+func (c *Conn) run(ctx context.Context, conn *ldap.Conn, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	result, err, abandoned := runContext(ctx, conn, f)
+	err = connError(conn, err, abandoned)
+	c.release(conn, abandoned || isConnError(err))
+	return result, err
+}
+
+// connError classifies an error returned by f in runContext. go-ldap reports
+// a connection that was closed under a request with a plain error, so it is
+// turned into a network error.
+func connError(conn *ldap.Conn, err error, abandoned bool) error {
+	var lerr *ldap.Error
+	if err != nil && !abandoned && conn.IsClosing() && !errors.As(err, &lerr) {
+		return ldap.NewError(ldap.ErrorNetwork, err)
+	}
+	return err
+}
+
+// runContext runs f with the connection, setting the request timeout from the
+// context deadline. If the context is done first the connection is closed to
+// unblock f, and abandoned is true.
+func runContext(ctx context.Context, conn *ldap.Conn, f func(*ldap.Conn) (interface{}, error)) (result interface{}, err error, abandoned bool) {
+	if ctx.Done() == nil {
+		result, err = f(conn)
+		return result, err, false
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err, false
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetTimeout(time.Until(deadline))
+		defer conn.SetTimeout(0)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, err = f(conn)
+	}()
+
+	select {
+	case <-done:
+		// A request that timed out leaves the connection in an unknown state.
+		if err != nil && ctx.Err() != nil {
+			return result, err, true
+		}
+		return result, err, false
+	case <-ctx.Done():
+		_ = conn.Close()
+		<-done
+		return nil, ctx.Err(), true
+	}
 }
 
 // ExecuteAs executes a function with a connection from the pool as a different user.
 func (c *Conn) ExecuteAs(dn string, password string, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
-	conn, err := c.get()
-	if err != nil {
-		return nil, err
-	}
-	defer c.put(conn)
+	return c.ExecuteAsContext(context.Background(), dn, password, f)
+}
 
-	err = bind(conn, dn, password)
-	if err != nil {
+// ExecuteAsContext executes a function with a connection from the pool as a different user.
+func (c *Conn) ExecuteAsContext(ctx context.Context, dn string, password string, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	return c.ExecuteAsAuthContext(ctx, SimpleAuth{DN: dn, Password: password}, f)
+}
+
+// ExecuteAsAuth executes a function with a connection from the pool bound
+// with the given authenticator.
+func (c *Conn) ExecuteAsAuth(auth Authenticator, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	return c.ExecuteAsAuthContext(context.Background(), auth, f)
+}
+
+// ExecuteAsAuthContext executes a function with a connection from the pool
+// bound with the given authenticator.
+func (c *Conn) ExecuteAsAuthContext(ctx context.Context, auth Authenticator, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	return c.ExecuteLdapContext(ctx, func(conn *ldap.Conn) (interface{}, error) {
+		return c.runAs(conn, auth, f)
+	})
+}
+
+// runAs runs f with the connection bound with the given authenticator, and
+// then binds the connection back to the pool identity. If that fails the
+// connection is closed, so that the pool does not hand it out again with the
+// rights of the other identity.
+func (c *Conn) runAs(conn *ldap.Conn, auth Authenticator, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	defer func() {
+		if err := c.rebind(conn); err != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	if err := c.authenticate(conn, c.serverURL(conn), auth); err != nil {
 		return nil, err
 	}
-	defer func(c *Conn, conn *ldap.Conn) {
-		_ = c.rebind(conn)
-	}(c, conn)
-
 	return f(conn)
 }
 
 // Search searches the LDAP server.
 func (c *Conn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
-	result, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
+	return c.SearchContext(context.Background(), request)
+}
+
+// SearchContext searches the LDAP server.
+func (c *Conn) SearchContext(ctx context.Context, request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result, err := c.executeRead(ctx, "search", request.BaseDN, func(conn *ldap.Conn) (interface{}, error) {
 		return conn.Search(request)
 	})
 	if err != nil {
@@ -258,7 +476,12 @@ func (c *Conn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
 
 // SearchWithPaging searches the LDAP server with paging.
 func (c *Conn) SearchWithPaging(request *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
-	result, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
+	return c.SearchWithPagingContext(context.Background(), request, pagingSize)
+}
+
+// SearchWithPagingContext searches the LDAP server with paging.
+func (c *Conn) SearchWithPagingContext(ctx context.Context, request *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	result, err := c.executeRead(ctx, "search", request.BaseDN, func(conn *ldap.Conn) (interface{}, error) {
 		return conn.SearchWithPaging(request, pagingSize)
 	})
 	if err != nil {
@@ -269,7 +492,12 @@ func (c *Conn) SearchWithPaging(request *ldap.SearchRequest, pagingSize uint32)
 
 // Add adds an entry to the LDAP server.
 func (c *Conn) Add(request *ldap.AddRequest) error {
-	_, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
+	return c.AddContext(context.Background(), request)
+}
+
+// AddContext adds an entry to the LDAP server.
+func (c *Conn) AddContext(ctx context.Context, request *ldap.AddRequest) error {
+	_, err := c.executeWrite(ctx, "add", request.DN, false, func(conn *ldap.Conn) (interface{}, error) {
 		return nil, conn.Add(request)
 	})
 	return err
@@ -277,23 +505,58 @@ func (c *Conn) Add(request *ldap.AddRequest) error {
 
 // Del deletes an entry from the LDAP server.
 func (c *Conn) Del(request *ldap.DelRequest) error {
-	_, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
+	return c.DelContext(context.Background(), request)
+}
+
+// DelContext deletes an entry from the LDAP server.
+func (c *Conn) DelContext(ctx context.Context, request *ldap.DelRequest) error {
+	_, err := c.executeWrite(ctx, "delete", request.DN, true, appliedOnRetry(func(conn *ldap.Conn) (interface{}, error) {
 		return nil, conn.Del(request)
-	})
+	}, ldap.LDAPResultNoSuchObject))
 	return err
 }
 
 // Modify modifies an entry on the LDAP server.
 func (c *Conn) Modify(request *ldap.ModifyRequest) error {
-	_, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
+	return c.ModifyContext(context.Background(), request)
+}
+
+// ModifyContext modifies an entry on the LDAP server.
+func (c *Conn) ModifyContext(ctx context.Context, request *ldap.ModifyRequest) error {
+	f := func(conn *ldap.Conn) (interface{}, error) {
 		return nil, conn.Modify(request)
+	}
+	if hasDelete(request) {
+		f = appliedOnRetry(f, ldap.LDAPResultNoSuchAttribute)
+	}
+	_, err := c.executeWrite(ctx, "modify", request.DN, isIdempotentModify(request), f)
+	return err
+}
+
+// ModifyDN renames or moves an entry on the LDAP server.
+func (c *Conn) ModifyDN(request *ldap.ModifyDNRequest) error {
+	return c.ModifyDNContext(context.Background(), request)
+}
+
+// ModifyDNContext renames or moves an entry on the LDAP server.
+func (c *Conn) ModifyDNContext(ctx context.Context, request *ldap.ModifyDNRequest) error {
+	_, err := c.executeWrite(ctx, "modify dn", request.DN, false, func(conn *ldap.Conn) (interface{}, error) {
+		return nil, conn.ModifyDN(request)
 	})
+	if err == nil {
+		c.sticky.touch(renamedDN(request))
+	}
 	return err
 }
 
 // PasswordModify modifies a user's password on the LDAP server.
 func (c *Conn) PasswordModify(request *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
-	result, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
+	return c.PasswordModifyContext(context.Background(), request)
+}
+
+// PasswordModifyContext modifies a user's password on the LDAP server.
+func (c *Conn) PasswordModifyContext(ctx context.Context, request *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
+	result, err := c.executeWrite(ctx, "password modify", request.UserIdentity, false, func(conn *ldap.Conn) (interface{}, error) {
 		return conn.PasswordModify(request)
 	})
 	if err != nil {
@@ -304,25 +567,46 @@ func (c *Conn) PasswordModify(request *ldap.PasswordModifyRequest) (*ldap.Passwo
 
 // Compare compares an attribute value on the LDAP server.
 func (c *Conn) Compare(dn string, attribute string, value string) (bool, error) {
-	result, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
+	return c.CompareContext(context.Background(), dn, attribute, value)
+}
+
+// CompareContext compares an attribute value on the LDAP server.
+func (c *Conn) CompareContext(ctx context.Context, dn string, attribute string, value string) (bool, error) {
+	result, err := c.executeRead(ctx, "compare", dn, func(conn *ldap.Conn) (interface{}, error) {
 		return conn.Compare(dn, attribute, value)
 	})
-	return result.(bool), err
+	if err != nil {
+		return false, err
+	}
+	return result.(bool), nil
 }
 
 // CheckBind checks the bind credentials on the LDAP server.
 func (c *Conn) CheckBind(dn string, password string) error {
-	_, err := c.ExecuteLdap(func(conn *ldap.Conn) (interface{}, error) {
-		err := bind(conn, dn, password)
-		defer func(c *Conn, conn *ldap.Conn) {
-			_ = c.rebind(conn)
-		}(c, conn)
-		return nil, err
+	return c.CheckBindContext(context.Background(), dn, password)
+}
+
+// CheckBindContext checks the bind credentials on the LDAP server.
+func (c *Conn) CheckBindContext(ctx context.Context, dn string, password string) error {
+	return c.CheckAuthContext(ctx, SimpleAuth{DN: dn, Password: password})
+}
+
+// CheckAuth checks that the authenticator can bind to the LDAP server.
+func (c *Conn) CheckAuth(auth Authenticator) error {
+	return c.CheckAuthContext(context.Background(), auth)
+}
+
+// CheckAuthContext checks that the authenticator can bind to the LDAP server.
+func (c *Conn) CheckAuthContext(ctx context.Context, auth Authenticator) error {
+	_, err := c.ExecuteLdapContext(ctx, func(conn *ldap.Conn) (interface{}, error) {
+		return c.runAs(conn, auth, func(*ldap.Conn) (interface{}, error) {
+			return nil, nil
+		})
 	})
 	return err
 }
 
 // rebind rebinds to the LDAP server.
 func (c *Conn) rebind(conn *ldap.Conn) error {
-	return bind(conn, c.bindDN, c.bindPassword)
+	return c.authenticate(conn, c.serverURL(conn), c.auth)
 }
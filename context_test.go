@@ -0,0 +1,67 @@
+package ldapx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn_SearchContextDeadline(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s.delay.Store(int64(200 * time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = conn.LookupContext(ctx, "")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+
+	// The abandoned connection must not be reused.
+	s.delay.Store(0)
+	_, err = conn.Lookup("")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, s.dials.Load())
+}
+
+func TestConn_SearchContextCanceled(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = conn.SearchContext(ctx, NewSearchRequest("", 0, 0, 0, 0, false, "(objectclass=*)", nil, nil))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestConn_GetContextWaitsForPool(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithPoolSize(0, 1, 1))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	lc, err := conn.get(context.Background())
+	require.NoError(t, err)
+	defer conn.put(lc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = conn.RootDSEContext(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
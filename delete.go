@@ -2,11 +2,16 @@ package ldapx
 
 import (
 	"github.com/go-ldap/ldap/v3"
-	"strings"
 )
 
 // DeleteAttributeValuesIgnoreCase deletes the given values from the attribute.
 func (e *Entry) DeleteAttributeValuesIgnoreCase(attr string, value []string, ignoreCase bool) {
+	e.deleteAttributeValues(attr, value, e.valueNormalizer(attr, ignoreCase))
+}
+
+// deleteAttributeValues deletes the values of the attribute that match any of
+// the given values under normalize.
+func (e *Entry) deleteAttributeValues(attr string, value []string, normalize normalizer) {
 	a := e.Attributes.Get(attr)
 	if a == nil {
 		return
@@ -17,7 +22,7 @@ func (e *Entry) DeleteAttributeValuesIgnoreCase(attr string, value []string, ign
 	for _, o := range a.Values {
 		var found bool
 		for _, d := range value {
-			if (o == d) || (ignoreCase && strings.EqualFold(o, d)) {
+			if (o == d) || normalize(o) == normalize(d) {
 				found = true
 				break
 			}
@@ -42,7 +47,7 @@ func (e *Entry) DeleteAttributeValuesIgnoreCase(attr string, value []string, ign
 		e.AddAttributeChange("delete", attr, nil)
 	} else {
 		// Some values were deleted
-		e.Attributes.PutEntryAttribute(ldap.NewEntryAttribute(attr, remainingValues))
+		e.Attributes.PutEntryAttribute(ldap.NewEntryAttribute(a.Name, remainingValues))
 		e.AddAttributeChange("delete", attr, deletedValues)
 	}
 }
@@ -0,0 +1,232 @@
+package ldapx
+
+import (
+	"sort"
+	"strings"
+)
+
+// DiffOption configures Diff and ApplyDesired.
+type DiffOption func(*diffOptions)
+
+// diffOptions holds the settings of Diff.
+type diffOptions struct {
+	ignoreCase        map[string]bool // Attributes whose values are compared ignoring case; nil for none
+	ignoreCaseAll     bool            // Compare the values of all attributes ignoring case
+	ignored           map[string]bool // Attributes left out of the diff
+	only              map[string]bool // Attributes the diff is limited to; nil for all
+	ignoreOperational bool            // Leave operational attributes out of the diff
+	replace           bool            // Emit a replace per changed attribute
+}
+
+// WithIgnoreCase compares the values of the given attributes, or of all
+// attributes if none are given, ignoring case.
+func WithIgnoreCase(attributes ...string) DiffOption {
+	return func(o *diffOptions) {
+		if len(attributes) == 0 {
+			o.ignoreCaseAll = true
+			return
+		}
+		if o.ignoreCase == nil {
+			o.ignoreCase = make(map[string]bool)
+		}
+		for _, a := range attributes {
+			o.ignoreCase[strings.ToLower(a)] = true
+		}
+	}
+}
+
+// WithIgnoredAttributes leaves the given attributes out of the diff.
+func WithIgnoredAttributes(attributes ...string) DiffOption {
+	return func(o *diffOptions) {
+		for _, a := range attributes {
+			o.ignored[strings.ToLower(a)] = true
+		}
+	}
+}
+
+// WithIgnoreOperational leaves operational attributes, such as
+// createTimestamp and entryUUID, out of the diff. The usage of the attribute
+// in the schema of the entries is used if they are bound to one; otherwise
+// a list of common operational attributes is.
+func WithIgnoreOperational() DiffOption {
+	return func(o *diffOptions) {
+		o.ignoreOperational = true
+	}
+}
+
+// WithReplaceChanges makes Diff emit a single replace of all the desired
+// values for each attribute that differs, instead of adding and deleting the
+// values that differ. Attributes that are not desired are replaced with no
+// values, which deletes them.
+func WithReplaceChanges() DiffOption {
+	return func(o *diffOptions) {
+		o.replace = true
+	}
+}
+
+// operationalAttributes holds the lowercased names of the operational
+// attributes commonly returned by servers, used when there is no schema.
+var operationalAttributes = map[string]bool{
+	"createtimestamp":       true,
+	"modifytimestamp":       true,
+	"creatorsname":          true,
+	"modifiersname":         true,
+	"entryuuid":             true,
+	"entrycsn":              true,
+	"entrydn":               true,
+	"contextcsn":            true,
+	"structuralobjectclass": true,
+	"subschemasubentry":     true,
+	"hassubordinates":       true,
+	"numsubordinates":       true,
+	"memberof":              true,
+	"pwdchangedtime":        true,
+	"pwdaccountlockedtime":  true,
+	"pwdfailuretime":        true,
+	"pwdhistory":            true,
+	"pwdgraceusetime":       true,
+	"pwdreset":              true,
+	"pwdpolicysubentry":     true,
+}
+
+// Diff returns the changes that make the current entry the same as the
+// desired entry: values that are desired and missing are added and values
+// that are not desired are deleted. An attribute that keeps none of its
+// values is deleted whole. The order of values, such as those of objectClass,
+// is not significant. Values are compared with the matching rules of the
+// schema the current entry, or else the desired entry, is bound to, and
+// exactly without one. A nil entry has no attributes.
+//
+// Changes are ordered by attribute name. For each attribute, values are
+// deleted before others are added, so that single-valued attributes can be
+// changed.
+func Diff(current, desired *Entry, opts ...DiffOption) []AttributeChange {
+	o := &diffOptions{ignored: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cur, want := NewAttributeMap(), NewAttributeMap()
+	var schema *LDAPSchema
+	if desired != nil {
+		want = desired.Attributes
+		schema = desired.schema
+	}
+	if current != nil {
+		cur = current.Attributes
+		if current.schema != nil {
+			schema = current.schema
+		}
+	}
+
+	keys := make(map[string]bool, len(cur)+len(want))
+	for k := range cur {
+		keys[k] = true
+	}
+	for k := range want {
+		keys[k] = true
+	}
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		if !o.ignores(k, schema) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []AttributeChange
+	for _, k := range names {
+		c, w := cur[k], want[k]
+		var curValues, wantValues []string
+		name := k
+		if c != nil {
+			curValues, name = c.Values, c.Name
+		}
+		if w != nil {
+			wantValues, name = w.Values, w.Name
+		}
+
+		normalize := exactValue
+		switch {
+		case o.ignoreCaseAll || o.ignoreCase[k]:
+			normalize = strings.ToLower
+		case schema != nil:
+			normalize = schema.normalizer(k)
+		}
+		added := valuesNotIn(wantValues, curValues, normalize)
+		deleted := valuesNotIn(curValues, wantValues, normalize)
+		if len(added) == 0 && len(deleted) == 0 {
+			continue
+		}
+
+		switch {
+		case o.replace:
+			changes = append(changes, AttributeChange{Action: "replace", Attr: name, Value: wantValues})
+		case len(deleted) == len(curValues) && len(curValues) > 0:
+			// Deleting every value deletes the attribute, as the mutators do.
+			changes = append(changes, AttributeChange{Action: "delete", Attr: name})
+			if len(added) > 0 {
+				changes = append(changes, AttributeChange{Action: "add", Attr: name, Value: added})
+			}
+		default:
+			if len(deleted) > 0 {
+				changes = append(changes, AttributeChange{Action: "delete", Attr: name, Value: deleted})
+			}
+			if len(added) > 0 {
+				changes = append(changes, AttributeChange{Action: "add", Attr: name, Value: added})
+			}
+		}
+	}
+	return changes
+}
+
+// onlyAttributes limits the diff to the given attributes.
+func onlyAttributes(attributes ...string) DiffOption {
+	return func(o *diffOptions) {
+		o.only = make(map[string]bool, len(attributes))
+		for _, a := range attributes {
+			o.only[strings.ToLower(a)] = true
+		}
+	}
+}
+
+// ignores returns true if the attribute, given by lowercased name, is left
+// out of the diff.
+func (o *diffOptions) ignores(attr string, schema *LDAPSchema) bool {
+	if o.ignored[attr] || (o.only != nil && !o.only[attr]) {
+		return true
+	}
+	if !o.ignoreOperational {
+		return false
+	}
+	if schema != nil {
+		if at := schema.AttributeType(attr); at != nil {
+			return at.Usage != UsageUserApplications
+		}
+	}
+	name, _, _ := strings.Cut(attr, ";")
+	return operationalAttributes[name]
+}
+
+// ApplyDesired changes the entry to match the desired entry, recording the
+// changes given by Diff through the mutators, so that Update applies them.
+// It returns the changes.
+func (e *Entry) ApplyDesired(desired *Entry, opts ...DiffOption) []AttributeChange {
+	changes := Diff(e, desired, opts...)
+	for _, c := range changes {
+		switch {
+		case c.Action == "replace" && len(c.Value) == 0:
+			e.Attributes.Delete(c.Attr)
+			e.AddAttributeChange("replace", c.Attr, nil)
+		case c.Action == "replace":
+			e.replaceAttributeValues(c.Attr, c.Value, exactValue)
+		case c.Action == "delete" && c.Value == nil:
+			e.DeleteAttribute(c.Attr)
+		case c.Action == "delete":
+			e.deleteAttributeValues(c.Attr, c.Value, exactValue)
+		case c.Action == "add":
+			e.addAttributeValues(c.Attr, c.Value, exactValue, false)
+		}
+	}
+	return changes
+}
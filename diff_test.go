@@ -0,0 +1,118 @@
+package ldapx
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diffEntries() (current, desired *Entry) {
+	current = NewEntryFromLdapEntry(ldap.NewEntry("uid=jdoe,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass":     {"top", "person", "inetOrgPerson"},
+		"uid":             {"jdoe"},
+		"cn":              {"John Doe"},
+		"mail":            {"jdoe@example.com", "john@example.com"},
+		"description":     {"old"},
+		"createTimestamp": {"20240101000000Z"},
+	}))
+
+	desired = NewEntry("uid=jdoe,ou=people,dc=example,dc=com")
+	desired.AddAttributeValues("objectClass", []string{"inetOrgPerson", "person", "top"})
+	desired.AddAttributeValue("uid", "jdoe")
+	desired.AddAttributeValue("cn", "John Doe")
+	desired.AddAttributeValues("mail", []string{"JDoe@example.com", "j.doe@example.com"})
+	desired.AddAttributeValue("telephoneNumber", "+1 555 0100")
+	return current, desired
+}
+
+func TestDiff(t *testing.T) {
+	current, desired := diffEntries()
+
+	assert.Equal(t, []AttributeChange{
+		{Action: "delete", Attr: "createTimestamp"},
+		{Action: "delete", Attr: "description"},
+		{Action: "delete", Attr: "mail"},
+		{Action: "add", Attr: "mail", Value: []string{"JDoe@example.com", "j.doe@example.com"}},
+		{Action: "add", Attr: "telephoneNumber", Value: []string{"+1 555 0100"}},
+	}, Diff(current, desired))
+
+	// Values that differ in case are the same when case is ignored.
+	assert.Equal(t, []AttributeChange{
+		{Action: "delete", Attr: "mail", Value: []string{"john@example.com"}},
+		{Action: "add", Attr: "mail", Value: []string{"j.doe@example.com"}},
+	}, Diff(current, desired, WithIgnoreCase("mail"), WithIgnoredAttributes("description", "telephoneNumber"), WithIgnoreOperational()))
+
+	assert.Equal(t, []AttributeChange{
+		{Action: "replace", Attr: "description"},
+		{Action: "replace", Attr: "mail", Value: []string{"JDoe@example.com", "j.doe@example.com"}},
+		{Action: "replace", Attr: "telephoneNumber", Value: []string{"+1 555 0100"}},
+	}, Diff(current, desired, WithReplaceChanges(), WithIgnoreOperational()))
+
+	assert.Empty(t, Diff(current, current.Clone()))
+	assert.Equal(t, []AttributeChange{{Action: "add", Attr: "telephoneNumber", Value: []string{"+1 555 0100"}}},
+		Diff(nil, desired, WithIgnoredAttributes("objectClass", "uid", "cn", "mail")))
+	assert.Equal(t, []AttributeChange{{Action: "delete", Attr: "uid"}},
+		Diff(current, nil, WithIgnoredAttributes("objectClass", "cn", "mail", "description", "createTimestamp")))
+}
+
+func TestDiff_Schema(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+	current, desired := diffEntries()
+	current.BindSchema(schema)
+
+	// mail and telephoneNumber are matched by the schema, and the operational
+	// attributes are known from it.
+	current.AddAttributeValue("telephoneNumber", "+15550100")
+	current.AddAttributeValue("entryUUID", "c0ffee00-0000-0000-0000-000000000000")
+	assert.Equal(t, []AttributeChange{
+		{Action: "delete", Attr: "description"},
+		{Action: "delete", Attr: "mail", Value: []string{"john@example.com"}},
+		{Action: "add", Attr: "mail", Value: []string{"j.doe@example.com"}},
+	}, Diff(current, desired, WithIgnoreOperational()))
+}
+
+func TestEntry_ApplyDesired(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("uid=jdoe,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass": {"top", "person"},
+		"uid":         {"jdoe"},
+		"cn":          {"John Doe"},
+		"mail":        {"jdoe@example.com", "john@example.com"},
+		"description": {"old"},
+	})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	current, err := conn.Lookup("uid=jdoe,ou=people,dc=example,dc=com")
+	require.NoError(t, err)
+	_, desired := diffEntries()
+	desired.DeleteAttribute("objectClass")
+	changes := current.ApplyDesired(desired, WithIgnoredAttributes("objectClass"))
+	assert.Equal(t, changes, current.Changes)
+	assert.ElementsMatch(t, []string{"JDoe@example.com", "j.doe@example.com"}, current.GetAttributeValues("mail"))
+	assert.False(t, current.AttributeExists("description"))
+
+	require.NoError(t, current.Update(conn))
+	stored := s.get("uid=jdoe,ou=people,dc=example,dc=com")
+	assert.Equal(t, []string{"top", "person"}, stored["objectClass"])
+	assert.ElementsMatch(t, []string{"JDoe@example.com", "j.doe@example.com"}, stored["mail"])
+	assert.Equal(t, []string{"+1 555 0100"}, stored["telephoneNumber"])
+	assert.Nil(t, stored["description"])
+
+	// The entry now matches, so there is nothing left to change.
+	assert.Empty(t, Diff(current, desired, WithIgnoredAttributes("objectClass")))
+
+	// Replace changes are recorded as replaces.
+	replaced := NewEntryFromLdapEntry(ldap.NewEntry("cn=x", map[string][]string{"cn": {"x"}, "sn": {"a"}}))
+	replaced.ApplyDesired(NewEntryFromLdapEntry(ldap.NewEntry("cn=x", map[string][]string{"cn": {"x"}, "mail": {"m"}})), WithReplaceChanges())
+	assert.Equal(t, []AttributeChange{
+		{Action: "replace", Attr: "mail", Value: []string{"m"}},
+		{Action: "replace", Attr: "sn"},
+	}, replaced.Changes)
+	assert.False(t, replaced.AttributeExists("sn"))
+	assert.Equal(t, "m", replaced.GetAttributeValue("mail"))
+}
@@ -2,6 +2,7 @@ package ldapx
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
@@ -16,20 +17,73 @@ func ParseDN(str string) (*DN, error) {
 	return (*DN)(dn), err
 }
 
+// NewDN creates a DN with a single RDN of the given attribute and value. The
+// value is taken as is, so it needs no escaping.
+func NewDN(attr, value string) *DN {
+	return (&DN{}).Child(attr, value)
+}
+
+// ChildDN returns the string form of the DN of the child of parent with an
+// RDN of the given attribute and value. The value is escaped, parent must
+// already be a valid DN string.
+func ChildDN(attr, value, parent string) string {
+	rdn := escapeDNAttributeType(attr) + "=" + EscapeDNValue(value)
+	if parent == "" {
+		return rdn
+	}
+	return rdn + "," + parent
+}
+
+// EscapeDNValue escapes an attribute value for use in the string form of a
+// DN, as described in RFC 4514.
+func EscapeDNValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case (i == 0 || i == len(value)-1) && c == ' ', i == 0 && c == '#':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '"', c == '+', c == ',', c == ';', c == '<', c == '>', c == '\\', c == '=':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c == 0x7f:
+			// Control characters are written as hex pairs; other bytes,
+			// including UTF-8 sequences, are written as they are.
+			_, _ = fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// escapeDNAttributeType escapes the characters that would end an attribute
+// type. Valid attribute types have none.
+func escapeDNAttributeType(attr string) string {
+	return strings.NewReplacer("\\", "\\\\", "=", "\\=", ",", "\\,", "+", "\\+").Replace(attr)
+}
+
+// String returns the string form of the DN, as described in RFC 4514. The
+// attribute types and their order are kept as they are.
+func (dn *DN) String() string {
+	rdns := make([]string, 0, len(dn.rdns()))
+	for _, rdn := range dn.rdns() {
+		rdns = append(rdns, rdnString(rdn))
+	}
+	return strings.Join(rdns, ",")
+}
+
 // ToString returns the string representation of the DN.
 func (dn *DN) ToString() string {
-	dnc := make([]string, 0, len(dn.RDNs))
-	for _, c := range dn.RDNs {
-		dnc = append(dnc, joinRDNAttrs(c))
-	}
-	return strings.Join(dnc, ",")
+	return dn.String()
 }
 
-// joinRDNAttrs joins the attributes of the RDN into a string.
-func joinRDNAttrs(rdn *ldap.RelativeDN) string {
+// rdnString returns the string form of the RDN.
+func rdnString(rdn *ldap.RelativeDN) string {
 	attrs := make([]string, 0, len(rdn.Attributes))
 	for _, a := range rdn.Attributes {
-		attrs = append(attrs, fmt.Sprintf("%s=%s", a.Type, a.Value))
+		attrs = append(attrs, escapeDNAttributeType(a.Type)+"="+EscapeDNValue(a.Value))
 	}
 	return strings.Join(attrs, "+")
 }
@@ -44,6 +98,161 @@ func (dn *DN) Append(attr, value string) {
 	}}, dn.RDNs...)
 }
 
+// Child returns the DN of the child with an RDN of the given attribute and
+// value. The DN itself is not changed.
+func (dn *DN) Child(attr, value string) *DN {
+	child := &DN{RDNs: make([]*ldap.RelativeDN, 0, len(dn.rdns())+1)}
+	child.RDNs = append(child.RDNs, &ldap.RelativeDN{
+		Attributes: []*ldap.AttributeTypeAndValue{{Type: attr, Value: value}},
+	})
+	child.RDNs = append(child.RDNs, dn.rdns()...)
+	return child
+}
+
+// RDN returns the first RDN of the DN, or nil for the empty DN.
+func (dn *DN) RDN() *ldap.RelativeDN {
+	if len(dn.rdns()) == 0 {
+		return nil
+	}
+	return dn.RDNs[0]
+}
+
+// Parent returns the DN of the parent, or nil for the empty DN.
+func (dn *DN) Parent() *DN {
+	if len(dn.rdns()) == 0 {
+		return nil
+	}
+	return &DN{RDNs: append([]*ldap.RelativeDN(nil), dn.RDNs[1:]...)}
+}
+
+// Depth returns the number of RDNs of the DN.
+func (dn *DN) Depth() int {
+	return len(dn.rdns())
+}
+
+// Join returns the DN made of the RDNs of the DN followed by those of base,
+// so that the DN is taken as relative to base. A nil base is the empty DN.
+func (dn *DN) Join(base *DN) *DN {
+	rdns := make([]*ldap.RelativeDN, 0, len(dn.rdns())+len(base.rdns()))
+	rdns = append(rdns, dn.rdns()...)
+	return &DN{RDNs: append(rdns, base.rdns()...)}
+}
+
+// rdns returns the RDNs of the DN. A nil DN is taken as the empty DN.
+func (dn *DN) rdns() []*ldap.RelativeDN {
+	if dn == nil {
+		return nil
+	}
+	return dn.RDNs
+}
+
+// RelativeTo returns the RDNs of the DN above base, which is the inverse of
+// Join. It returns false if the DN is not base or below it, or either is nil.
+func (dn *DN) RelativeTo(base *DN) (*DN, bool) {
+	if dn == nil || base == nil {
+		return nil, false
+	}
+	n := len(dn.RDNs) - len(base.RDNs)
+	if n < 0 || !(&DN{RDNs: dn.RDNs[n:]}).Equal(base) {
+		return nil, false
+	}
+	return &DN{RDNs: append([]*ldap.RelativeDN(nil), dn.RDNs[:n]...)}, true
+}
+
+// Equal returns true if the DNs are the same once normalized without a
+// schema. A nil DN is only equal to another nil DN.
+func (dn *DN) Equal(other *DN) bool {
+	if dn == nil || other == nil {
+		return dn == other
+	}
+	return dn.Normalize(nil).String() == other.Normalize(nil).String()
+}
+
+// IsDescendantOf returns true if the DN is below base, at any depth.
+func (dn *DN) IsDescendantOf(base *DN) bool {
+	rel, ok := dn.RelativeTo(base)
+	return ok && rel.Depth() > 0
+}
+
+// IsChildOf returns true if the DN is directly below parent.
+func (dn *DN) IsChildOf(parent *DN) bool {
+	rel, ok := dn.RelativeTo(parent)
+	return ok && rel.Depth() == 1
+}
+
+// dnTypeNames maps the OIDs and long names of the attribute types commonly
+// used in DNs, in lower case, to their short names.
+var dnTypeNames = map[string]string{
+	"2.5.4.3":                    "cn",
+	"commonname":                 "cn",
+	"2.5.4.4":                    "sn",
+	"surname":                    "sn",
+	"2.5.4.6":                    "c",
+	"countryname":                "c",
+	"2.5.4.7":                    "l",
+	"localityname":               "l",
+	"2.5.4.8":                    "st",
+	"stateorprovincename":        "st",
+	"2.5.4.9":                    "street",
+	"streetaddress":              "street",
+	"2.5.4.10":                   "o",
+	"organizationname":           "o",
+	"2.5.4.11":                   "ou",
+	"organizationalunitname":     "ou",
+	"2.5.4.12":                   "title",
+	"0.9.2342.19200300.100.1.1":  "uid",
+	"userid":                     "uid",
+	"0.9.2342.19200300.100.1.25": "dc",
+	"domaincomponent":            "dc",
+}
+
+// Normalize returns the DN in a normal form, so that DNs that name the same
+// entry have the same string form. Attribute types are lowercased and OIDs
+// and aliases are replaced by the short name. With a schema, types are
+// resolved and values are folded with the equality matching rule of their
+// attribute. Other attributes, or all of them without a schema, have the
+// common naming attributes resolved and values compared ignoring case. The
+// values of a multi-valued RDN are sorted.
+func (dn *DN) Normalize(schema *LDAPSchema) *DN {
+	n := &DN{RDNs: make([]*ldap.RelativeDN, 0, len(dn.rdns()))}
+	for _, rdn := range dn.rdns() {
+		attrs := make([]*ldap.AttributeTypeAndValue, 0, len(rdn.Attributes))
+		for _, a := range rdn.Attributes {
+			typ := a.Type
+			if len(typ) > 4 && strings.EqualFold(typ[:4], "oid.") {
+				typ = typ[4:]
+			}
+
+			var at *AttributeType
+			if schema != nil {
+				at = schema.AttributeType(typ)
+			}
+			var value string
+			if at != nil {
+				// Types without a name keep their OID.
+				if at.Name != "" {
+					typ = at.Name
+				}
+				value = schema.normalizer(typ)(a.Value)
+			} else {
+				if name, ok := dnTypeNames[strings.ToLower(typ)]; ok {
+					typ = name
+				}
+				value = normalizeCaseIgnore(a.Value)
+			}
+			attrs = append(attrs, &ldap.AttributeTypeAndValue{Type: strings.ToLower(typ), Value: value})
+		}
+		sort.Slice(attrs, func(i, j int) bool {
+			if attrs[i].Type != attrs[j].Type {
+				return attrs[i].Type < attrs[j].Type
+			}
+			return attrs[i].Value < attrs[j].Value
+		})
+		n.RDNs = append(n.RDNs, &ldap.RelativeDN{Attributes: attrs})
+	}
+	return n
+}
+
 // IsDN returns true if the string is a DN.
 func IsDN(dn string) bool {
 	_, err := ParseDN(dn)
@@ -0,0 +1,144 @@
+package ldapx
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseDN(t *testing.T, s string) *DN {
+	t.Helper()
+	dn, err := ParseDN(s)
+	require.NoError(t, err)
+	return dn
+}
+
+func TestEscapeDNValue(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"Sales", "Sales"},
+		{"Sales, EMEA", `Sales\, EMEA`},
+		{"a+b=c", `a\+b\=c`},
+		{`"quoted" <x>;\`, `\"quoted\" \<x\>\;\\`},
+		{" leading and trailing ", `\ leading and trailing\ `},
+		{"#hash", `\#hash`},
+		{"mid#dle", "mid#dle"},
+		{"nul\x00", `nul\00`},
+		{"café", "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			escaped := EscapeDNValue(tt.value)
+			assert.Equal(t, tt.want, escaped)
+
+			// The escaped value parses back to the value.
+			dn := mustParseDN(t, "cn="+escaped)
+			assert.Equal(t, tt.value, dn.RDN().Attributes[0].Value)
+		})
+	}
+}
+
+func TestDN_String(t *testing.T) {
+	dn := NewDN("dc", "com").Child("dc", "example").Child("ou", "Sales, EMEA").Child("CN", " Smith+Jones ")
+	assert.Equal(t, `CN=\ Smith\+Jones\ ,ou=Sales\, EMEA,dc=example,dc=com`, dn.String())
+	assert.Equal(t, dn.String(), dn.ToString())
+
+	parsed := mustParseDN(t, dn.String())
+	assert.Equal(t, " Smith+Jones ", parsed.RDN().Attributes[0].Value)
+	assert.Equal(t, "Sales, EMEA", parsed.RDNs[1].Attributes[0].Value)
+
+	assert.Equal(t, "cn=a+sn=b,dc=example", mustParseDN(t, "cn=a+sn=b,dc=example").String())
+	assert.Equal(t, "", mustParseDN(t, "").String())
+}
+
+func TestChildDN(t *testing.T) {
+	assert.Equal(t, `ou=R\,D,dc=example,dc=com`, ChildDN("ou", "R,D", "dc=example,dc=com"))
+	assert.Equal(t, `ou=R\,D`, ChildDN("ou", "R,D", ""))
+}
+
+func TestDN_Normalize(t *testing.T) {
+	dn := mustParseDN(t, "CommonName=John  SMITH+2.5.4.4=Smith,OID.2.5.4.11=People,DC=Example,dc=COM")
+	assert.Equal(t, "cn=john smith+sn=smith,ou=people,dc=example,dc=com", dn.Normalize(nil).String())
+
+	s := loadSchemaDump(t, "openldap.ldif")
+	assert.Equal(t, "cn=john smith+sn=smith,ou=people,dc=example,dc=com", dn.Normalize(s).String())
+
+	// Values of attributes with an exact matching rule keep their case.
+	assert.Equal(t, "userpassword=Secret", mustParseDN(t, "userPassword=Secret").Normalize(s).String())
+
+	// Types without a name keep their OID.
+	unnamed := NewLDAPSchemaFromLdapEntry(ldap.NewEntry("cn=schema", map[string][]string{
+		"attributeTypes": {"( 1.3.6.1.4.1.99999.1 EQUALITY caseIgnoreMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )"},
+	}))
+	assert.Equal(t, "1.3.6.1.4.1.99999.1=foo,dc=example", mustParseDN(t, "OID.1.3.6.1.4.1.99999.1=Foo,dc=Example").Normalize(unnamed).String())
+}
+
+func TestDN_Navigation(t *testing.T) {
+	base := mustParseDN(t, "dc=example,dc=com")
+	people := mustParseDN(t, "ou=People,DC=Example,dc=com")
+	user := mustParseDN(t, "uid=jdoe,ou=people,dc=example,dc=com")
+
+	assert.True(t, people.Equal(mustParseDN(t, "OU=people, dc=example, dc=com")))
+	assert.False(t, people.Equal(base))
+
+	assert.Equal(t, "ou=people,dc=example,dc=com", user.Parent().String())
+	assert.Equal(t, "uid=jdoe", (&DN{RDNs: user.RDNs[:1]}).String())
+	assert.Equal(t, "jdoe", user.RDN().Attributes[0].Value)
+	assert.Equal(t, 4, user.Depth())
+	assert.Nil(t, (&DN{}).Parent())
+	assert.Nil(t, (&DN{}).RDN())
+
+	assert.True(t, user.IsDescendantOf(base))
+	assert.True(t, user.IsChildOf(people))
+	assert.False(t, user.IsChildOf(base))
+	assert.False(t, base.IsDescendantOf(base))
+	assert.False(t, base.IsDescendantOf(user))
+	assert.False(t, mustParseDN(t, "uid=jdoe,dc=other,dc=com").IsDescendantOf(base))
+
+	rel, ok := user.RelativeTo(base)
+	require.True(t, ok)
+	assert.Equal(t, "uid=jdoe,ou=people", rel.String())
+	assert.True(t, rel.Join(base).Equal(user))
+
+	rel, ok = base.RelativeTo(base)
+	require.True(t, ok)
+	assert.Equal(t, 0, rel.Depth())
+
+	_, ok = base.RelativeTo(user)
+	assert.False(t, ok)
+
+	// Nil DNs are not related to any other.
+	_, ok = base.RelativeTo(nil)
+	assert.False(t, ok)
+	assert.False(t, base.Equal(nil))
+	assert.False(t, base.IsDescendantOf(nil))
+	assert.False(t, base.IsChildOf(nil))
+	assert.False(t, (*DN)(nil).IsDescendantOf(base))
+
+	// Otherwise nil DNs are taken as the empty DN.
+	var nilDN *DN
+	tests := []struct {
+		name string
+		dn   *DN
+		want string
+	}{
+		{"nil", nilDN, ""},
+		{"nil parent", nilDN.Parent(), ""},
+		{"nil normalized", nilDN.Normalize(nil), ""},
+		{"child of nil", nilDN.Child("dc", "com"), "dc=com"},
+		{"nil joined to base", nilDN.Join(base), "dc=example,dc=com"},
+		{"base joined to nil", base.Join(nil), "dc=example,dc=com"},
+		{"nil joined to nil", nilDN.Join(nil), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.dn.String())
+		})
+	}
+	assert.Equal(t, "", nilDN.ToString())
+	assert.Nil(t, nilDN.RDN())
+	assert.Equal(t, 0, nilDN.Depth())
+}
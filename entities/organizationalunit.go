@@ -1,13 +1,11 @@
 package entities
 
 import (
-	"fmt"
-
 	"github.com/jbirdman/ldapx"
 )
 
 func NewOrganizationalUnit(baseDN, ou string) *ldapx.Entry {
-	entry := ldapx.NewEntry(fmt.Sprintf("ou=%s,%s", ou, baseDN))
+	entry := ldapx.NewEntry(ldapx.ChildDN("ou", ou, baseDN))
 
 	entry.ReplaceAttributeValues("objectclass", []string{"top", "organizationalUnit"})
 	entry.ReplaceAttributeValue("ou", ou)
@@ -1,10 +1,7 @@
 package ldapx
 
 import (
-	"encoding/json"
-	"errors"
-	"fmt"
-	"sort"
+	"context"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
@@ -24,6 +21,7 @@ type Entry struct {
 	Changes            []AttributeChange `json:"changes,omitempty"`    // Changes is a list of changes to be applied to the entry
 	committed          bool              // committed is true if the entry has been committed to the server
 	originalAttributes AttributeMap      // originalAttributes is a copy of the attributes when the entry was created
+	schema             *LDAPSchema       // schema, if set, provides the matching rules used to compare values
 }
 
 var _ MutableEntry = &Entry{}
@@ -78,6 +76,7 @@ func NewEntryFromLdapEntry(entry *ldap.Entry) *Entry {
 
 		// Copy in the attributes from the ldap entry
 		for _, a := range entry.Attributes {
+			a = copyEntryAttribute(a)
 			e.Attributes.PutEntryAttribute(a)
 			e.originalAttributes.PutEntryAttribute(a)
 		}
@@ -86,6 +85,16 @@ func NewEntryFromLdapEntry(entry *ldap.Entry) *Entry {
 	return e
 }
 
+// copyEntryAttribute returns a copy of the attribute whose Values and
+// ByteValues hold the same values. An attribute built with byte values only
+// gets string values holding the same bytes.
+func copyEntryAttribute(a *ldap.EntryAttribute) *ldap.EntryAttribute {
+	if len(a.Values) < len(a.ByteValues) {
+		return ldap.NewEntryAttribute(a.Name, bytesToStrings(a.ByteValues))
+	}
+	return ldap.NewEntryAttribute(a.Name, append([]string(nil), a.Values...))
+}
+
 // ToLdapEntry converts the ldapx entry to a ldap entry
 func (e *Entry) ToLdapEntry() *ldap.Entry {
 	// Copy the attributes to a map
@@ -117,24 +126,10 @@ func (e *Entry) AttributeNames() []string {
 	return e.Attributes.AttributeNames()
 }
 
-// ToLDIF converts the entry to LDIF
+// ToLDIF converts the entry to an LDIF content record.
 func (e *Entry) ToLDIF() string {
-	// Create a string builder
 	var b strings.Builder
-	_, _ = fmt.Fprintf(&b, "dn: %s\n", e.DN)
-
-	// Sort the attributes
-	keys := e.AttributeNames()
-	sort.Strings(keys)
-
-	// Print the attributes
-	for _, k := range keys {
-		for _, v := range e.Attributes.Get(k).Values {
-			_, _ = fmt.Fprintf(&b, "%s: %s\n", k, v)
-		}
-	}
-
-	// Return the string
+	writeEntryLDIF(&b, e)
 	return b.String()
 }
 
@@ -177,6 +172,11 @@ func (e *Entry) AddAttributeChange(action string, attr string, value []string) {
 }
 
 func (e *Entry) Update(conn *Conn) error {
+	return e.UpdateContext(context.Background(), conn)
+}
+
+// UpdateContext applies the pending changes to the server.
+func (e *Entry) UpdateContext(ctx context.Context, conn *Conn) error {
 	// Update the entry
 	if !e.Changed() {
 		return nil
@@ -184,20 +184,27 @@ func (e *Entry) Update(conn *Conn) error {
 
 	// Check if the entry has been committed
 	if e.committed {
-		return errors.New("entry can only be updated once")
+		return &OpError{Op: "update", DN: e.DN, Err: ErrAlreadyCommitted}
+	}
+
+	// Validate the entry against the schema if enabled
+	if conn.validate && conn.schema != nil {
+		if violations := e.Validate(conn.schema); len(violations) > 0 {
+			return &OpError{Op: "update", DN: e.DN, Err: &ValidationError{Violations: violations}}
+		}
 	}
 	e.committed = true
 
 	switch e.ChangeType {
 	case ChangeAdd:
 		// Add the entry
-		return conn.Add(buildAddRequest(e.DN, e.Changes))
+		return conn.AddContext(ctx, buildAddRequest(e.DN, e.Changes))
 	case ChangeUpdate:
 		// Modify the entry
-		return conn.Modify(buildModifyRequest(e.DN, e.Changes))
+		return conn.ModifyContext(ctx, buildModifyRequest(e.DN, e.Changes))
 	case ChangeDelete:
 		// Delete the entry
-		return conn.Del(buildDelRequest(e.DN))
+		return conn.DelContext(ctx, buildDelRequest(e.DN))
 	}
 
 	return nil
@@ -206,6 +213,7 @@ func (e *Entry) Update(conn *Conn) error {
 func (e *Entry) Clone() *Entry {
 	// Clone the entry
 	dest := NewEntry(e.DN)
+	dest.schema = e.schema
 
 	for _, a := range e.AttributeNames() {
 		dest.AddAttributeValues(a, e.GetAttributeValues(a))
@@ -245,6 +253,10 @@ func buildModifyRequest(dn string, changes []AttributeChange) *ldap.ModifyReques
 			r.Replace(change.Attr, change.Value)
 		case "delete":
 			r.Delete(change.Attr, change.Value)
+		case "increment":
+			for _, v := range change.Value {
+				r.Increment(change.Attr, v)
+			}
 		}
 	}
 
@@ -260,12 +272,3 @@ func buildDelRequest(dn string) *ldap.DelRequest {
 func (e *Entry) RenameAttribute(from, to string) {
 	e.Attributes.Rename(from, to)
 }
-
-// ToJSON returns the entry as a JSON string
-func (e *Entry) ToJSON() string {
-	b, err := json.Marshal(e)
-	if err != nil {
-		panic(err)
-	}
-	return string(b)
-}
@@ -0,0 +1,239 @@
+package ldapx
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Entries are encoded as JSON objects of the form
+//
+//	{
+//	  "dn": "cn=Test,dc=example,dc=com",
+//	  "change_type": "update",
+//	  "attributes": {"cn": ["Test"], "jpegPhoto": {"base64": ["/9j/"]}},
+//	  "changes": [{"action": "replace", "attr": "cn", "values": ["Test"]}],
+//	  "original_attributes": {"cn": ["test"]},
+//	  "committed": false
+//	}
+//
+// Attributes are keyed by name, in the case the entry holds them. Their values
+// are a list of strings, or an object with a list of base64 strings if any
+// value is not valid UTF-8. The values of changes are encoded the same way,
+// under "values" or "base64". Empty fields other than "dn" and "change_type"
+// are omitted. The schema an entry is bound to is not encoded.
+
+// jsonValues are attribute values encoded as JSON.
+type jsonValues []string
+
+// MarshalJSON encodes the values as a list of strings, or as an object with a
+// list of base64 strings if any value is not valid UTF-8.
+func (v jsonValues) MarshalJSON() ([]byte, error) {
+	if !isBinary(v) {
+		return json.Marshal([]string(v))
+	}
+	return json.Marshal(struct {
+		Base64 []string `json:"base64"`
+	}{encodeBase64(v)})
+}
+
+// UnmarshalJSON decodes values encoded by MarshalJSON.
+func (v *jsonValues) UnmarshalJSON(b []byte) error {
+	var values []string
+	if err := json.Unmarshal(b, &values); err == nil {
+		*v = values
+		return nil
+	}
+	var binary struct {
+		Base64 []string `json:"base64"`
+	}
+	if err := json.Unmarshal(b, &binary); err != nil {
+		return fmt.Errorf("attribute values must be a list of strings or an object with base64 values: %w", err)
+	}
+	values, err := decodeBase64(binary.Base64)
+	if err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// isBinary returns true if any value is not valid UTF-8.
+func isBinary(values []string) bool {
+	for _, v := range values {
+		if !utf8.ValidString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeBase64 encodes the values in base64.
+func encodeBase64(values []string) []string {
+	encoded := make([]string, len(values))
+	for i, v := range values {
+		encoded[i] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	return encoded
+}
+
+// decodeBase64 decodes base64 values.
+func decodeBase64(values []string) ([]string, error) {
+	decoded := make([]string, len(values))
+	for i, v := range values {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 value: %w", err)
+		}
+		decoded[i] = string(b)
+	}
+	return decoded, nil
+}
+
+// MarshalJSON encodes the attributes as an object keyed by attribute name.
+func (m AttributeMap) MarshalJSON() ([]byte, error) {
+	attrs := make(map[string]jsonValues, len(m))
+	for _, a := range m {
+		attrs[a.Name] = a.Values
+	}
+	return json.Marshal(attrs)
+}
+
+// UnmarshalJSON decodes attributes encoded by MarshalJSON.
+func (m *AttributeMap) UnmarshalJSON(b []byte) error {
+	var attrs map[string]jsonValues
+	if err := json.Unmarshal(b, &attrs); err != nil {
+		return err
+	}
+	*m = NewAttributeMap()
+	for name, values := range attrs {
+		m.PutEntryAttribute(ldap.NewEntryAttribute(name, values))
+	}
+	return nil
+}
+
+// jsonChange is the JSON form of an AttributeChange.
+type jsonChange struct {
+	Action string   `json:"action"`
+	Attr   string   `json:"attr"`
+	Values []string `json:"values,omitempty"`
+	Base64 []string `json:"base64,omitempty"`
+}
+
+// MarshalJSON encodes the change as an object with the action, the attribute
+// and its values, which are base64 encoded if any is not valid UTF-8.
+func (c AttributeChange) MarshalJSON() ([]byte, error) {
+	j := jsonChange{Action: c.Action, Attr: c.Attr, Values: c.Value}
+	if isBinary(c.Value) {
+		j.Values, j.Base64 = nil, encodeBase64(c.Value)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a change encoded by MarshalJSON.
+func (c *AttributeChange) UnmarshalJSON(b []byte) error {
+	var j jsonChange
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	values := j.Values
+	if len(j.Base64) > 0 {
+		var err error
+		if values, err = decodeBase64(j.Base64); err != nil {
+			return err
+		}
+	}
+	*c = AttributeChange{Action: j.Action, Attr: j.Attr, Value: values}
+	return nil
+}
+
+// jsonEntry is the JSON form of an Entry.
+type jsonEntry struct {
+	DN                 string            `json:"dn"`
+	ChangeType         string            `json:"change_type"`
+	Attributes         AttributeMap      `json:"attributes,omitempty"`
+	Changes            []AttributeChange `json:"changes,omitempty"`
+	OriginalAttributes AttributeMap      `json:"original_attributes,omitempty"`
+	Committed          bool              `json:"committed,omitempty"`
+}
+
+// MarshalJSON encodes the entry with its pending changes and the state it
+// was read in. It returns an error if the change type is not valid.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	if err := checkChangeType(e.ChangeType); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonEntry{
+		DN:                 e.DN,
+		ChangeType:         e.ChangeType,
+		Attributes:         e.Attributes,
+		Changes:            e.Changes,
+		OriginalAttributes: e.originalAttributes,
+		Committed:          e.committed,
+	})
+}
+
+// UnmarshalJSON decodes an entry encoded by MarshalJSON.
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var j jsonEntry
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	if err := checkChangeType(j.ChangeType); err != nil {
+		return err
+	}
+	if j.Attributes == nil {
+		j.Attributes = NewAttributeMap()
+	}
+	if j.OriginalAttributes == nil {
+		j.OriginalAttributes = NewAttributeMap()
+	}
+
+	*e = Entry{
+		DN:                 j.DN,
+		ChangeType:         j.ChangeType,
+		Attributes:         j.Attributes,
+		Changes:            j.Changes,
+		committed:          j.Committed,
+		originalAttributes: j.OriginalAttributes,
+	}
+	return nil
+}
+
+// checkChangeType returns an error if the change type is not valid.
+func checkChangeType(changeType string) error {
+	switch changeType {
+	case ChangeAdd, ChangeUpdate, ChangeDelete:
+		return nil
+	}
+	return fmt.Errorf("invalid change type %q", changeType)
+}
+
+// ToJSON returns the entry as a JSON string, or "" if it cannot be encoded.
+//
+// Deprecated: Use ToJSONE, which returns the error.
+func (e *Entry) ToJSON() string {
+	s, _ := e.ToJSONE()
+	return s
+}
+
+// ToJSONE returns the entry as a JSON string.
+func (e *Entry) ToJSONE() (string, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// EntryFromJSON decodes an entry from its JSON form.
+func EntryFromJSON(s string) (*Entry, error) {
+	e := &Entry{}
+	if err := json.Unmarshal([]byte(s), e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,110 @@
+package ldapx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntry_JSON(t *testing.T) {
+	e := NewEntryFromLdapEntry(ldap.NewEntry("cn=Test,dc=example,dc=com", map[string][]string{
+		"objectClass": {"person"},
+		"cn":          {"test"},
+		"jpegPhoto":   {"\xff\xd8\xff"},
+	}))
+	e.ReplaceAttributeValue("cn", "Test")
+	e.AddAttributeValue("userCertificate;binary", "\x30\x82")
+
+	s, err := e.ToJSONE()
+	require.NoError(t, err)
+	assert.Equal(t, s, e.ToJSON())
+	assert.JSONEq(t, `{
+		"dn": "cn=Test,dc=example,dc=com",
+		"change_type": "update",
+		"attributes": {
+			"objectClass": ["person"],
+			"cn": ["Test"],
+			"jpegPhoto": {"base64": ["/9j/"]},
+			"userCertificate;binary": {"base64": ["MII="]}
+		},
+		"changes": [
+			{"action": "replace", "attr": "cn", "values": ["Test"]},
+			{"action": "add", "attr": "userCertificate;binary", "base64": ["MII="]}
+		],
+		"original_attributes": {
+			"objectClass": ["person"],
+			"cn": ["test"],
+			"jpegPhoto": {"base64": ["/9j/"]}
+		}
+	}`, s)
+
+	decoded, err := EntryFromJSON(s)
+	require.NoError(t, err)
+	assert.Equal(t, e.DN, decoded.DN)
+	assert.Equal(t, ChangeUpdate, decoded.ChangeType)
+	assert.Equal(t, e.Changes, decoded.Changes)
+	assert.ElementsMatch(t, []string{"objectClass", "cn", "jpegPhoto", "userCertificate;binary"}, decoded.AttributeNames())
+	assert.Equal(t, "\xff\xd8\xff", decoded.GetAttributeValue("jpegphoto"))
+	assert.Equal(t, []string{"test"}, decoded.originalAttributes.Get("cn").Values)
+	assert.False(t, decoded.committed)
+
+	// The decoded entry carries on tracking changes.
+	decoded.ReplaceAttributeValue("cn", "Test")
+	assert.Len(t, decoded.Changes, 2)
+	decoded.AddAttributeValue("mail", "test@example.com")
+	assert.Len(t, decoded.Changes, 3)
+}
+
+func TestEntry_JSONCommitted(t *testing.T) {
+	e := NewEntry("cn=new,dc=example,dc=com")
+	e.committed = true
+
+	b, err := json.Marshal(e)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"dn": "cn=new,dc=example,dc=com", "change_type": "add", "committed": true}`, string(b))
+
+	var decoded Entry
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.True(t, decoded.committed)
+	assert.NotNil(t, decoded.Attributes)
+	decoded.AddAttributeValue("cn", "new")
+	assert.Equal(t, "new", decoded.GetAttributeValue("cn"))
+}
+
+func TestEntry_JSONErrors(t *testing.T) {
+	_, err := EntryFromJSON(`{"dn": "cn=x", "change_type": "rename"}`)
+	assert.Error(t, err)
+
+	_, err = EntryFromJSON(`{"dn": "cn=x", "change_type": "add", "attributes": {"cn": 1}}`)
+	assert.Error(t, err)
+
+	_, err = EntryFromJSON(`{"dn": "cn=x", "change_type": "add", "attributes": {"cn": {"base64": ["!"]}}}`)
+	assert.Error(t, err)
+
+	// Entries that cannot be encoded return an error rather than panicking.
+	e := NewEntry("cn=x")
+	e.ChangeType = "rename"
+	assert.NotPanics(t, func() {
+		_, err = e.ToJSONE()
+		assert.Error(t, err)
+		assert.Equal(t, "", e.ToJSON())
+	})
+}
+
+func TestAttributeMap_JSON(t *testing.T) {
+	m := NewAttributeMap()
+	m.PutEntryAttribute(ldap.NewEntryAttribute("displayName", []string{"Test", "tést"}))
+
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"displayName": ["Test", "tést"]}`, string(b))
+
+	var decoded AttributeMap
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.NotNil(t, decoded.Get("displayname"))
+	assert.Equal(t, "displayName", decoded.Get("displayname").Name)
+	assert.Equal(t, []string{"Test", "tést"}, decoded.Get("displayname").Values)
+}
@@ -0,0 +1,116 @@
+package ldapx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrNotFound            = errors.New("entry not found")                              // ErrNotFound is returned when an entry does not exist or is not visible
+	ErrMultipleEntries     = errors.New("multiple entries matched")                     // ErrMultipleEntries is returned when a search for one entry matched several
+	ErrAlreadyCommitted    = errors.New("entry can only be updated once")               // ErrAlreadyCommitted is returned when an entry is updated a second time
+	ErrNotCommitted        = errors.New("entry has not been updated")                   // ErrNotCommitted is returned when undoing an entry that was not updated
+	ErrAlreadyExists       = errors.New("entry already exists")                         // ErrAlreadyExists is returned when adding an entry that exists
+	ErrConstraintViolation = errors.New("constraint violation")                         // ErrConstraintViolation is returned when a change violates a constraint or the schema
+	ErrNoSuchAttribute     = errors.New("no such attribute")                            // ErrNoSuchAttribute is returned when an attribute or value does not exist
+	ErrValueExists         = errors.New("attribute or value exists")                    // ErrValueExists is returned when adding a value that already exists
+	ErrInvalidCredentials  = errors.New("invalid credentials")                          // ErrInvalidCredentials is returned when a bind is rejected
+	ErrInsufficientAccess  = errors.New("insufficient access rights")                   // ErrInsufficientAccess is returned when the bound identity may not perform the operation
+	ErrUnavailable         = errors.New("server unavailable")                           // ErrUnavailable is returned when the server is busy, unavailable or unreachable
+	ErrPoolTimeout         = errors.New("timed out waiting for a pooled connection")    // ErrPoolTimeout is returned when no pooled connection became available in time
+	ErrNoServers           = errors.New("no LDAP servers configured")                   // ErrNoServers is returned when no LDAP server URL was given
+	ErrCleartextBind       = errors.New("refusing to bind over a cleartext connection") // ErrCleartextBind is returned when StartTLS is required and a bind would be sent unencrypted
+	ErrTooManyDeletes      = errors.New("too many entries to delete")                   // ErrTooManyDeletes is returned when a reconciliation would delete more entries than allowed
+)
+
+// codeErrors maps LDAP result codes to the sentinel errors they match.
+var codeErrors = map[uint16]error{
+	ldap.LDAPResultNoSuchObject:             ErrNotFound,
+	ldap.LDAPResultEntryAlreadyExists:       ErrAlreadyExists,
+	ldap.LDAPResultConstraintViolation:      ErrConstraintViolation,
+	ldap.LDAPResultObjectClassViolation:     ErrConstraintViolation,
+	ldap.LDAPResultNotAllowedOnRDN:          ErrConstraintViolation,
+	ldap.LDAPResultInvalidAttributeSyntax:   ErrConstraintViolation,
+	ldap.LDAPResultNoSuchAttribute:          ErrNoSuchAttribute,
+	ldap.LDAPResultAttributeOrValueExists:   ErrValueExists,
+	ldap.LDAPResultInvalidCredentials:       ErrInvalidCredentials,
+	ldap.LDAPResultInsufficientAccessRights: ErrInsufficientAccess,
+	ldap.LDAPResultBusy:                     ErrUnavailable,
+	ldap.LDAPResultUnavailable:              ErrUnavailable,
+	ldap.LDAPResultServerDown:               ErrUnavailable,
+	ldap.LDAPResultConnectError:             ErrUnavailable,
+	ldap.ErrorNetwork:                       ErrUnavailable,
+}
+
+// OpError is the error returned by ldapx operations. It records the
+// operation, the DN it applied to and the LDAP result code, if any.
+type OpError struct {
+	Op   string // Operation, such as "search", "add" or "bind"
+	DN   string // DN the operation applied to
+	Code uint16 // LDAP result code, or 0 if the error did not come from the server
+	Err  error  // Underlying error
+}
+
+// Error returns the error message.
+func (e *OpError) Error() string {
+	if e.DN == "" {
+		return fmt.Sprintf("ldapx: %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("ldapx: %s %s: %v", e.Op, e.DN, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the result code corresponds to the target sentinel error.
+func (e *OpError) Is(target error) bool {
+	sentinel, ok := codeErrors[e.Code]
+	return ok && sentinel == target
+}
+
+// wrapError wraps err in an OpError for the operation. Errors that are
+// already OpErrors are returned unchanged.
+func wrapError(op, dn string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		return err
+	}
+
+	e := &OpError{Op: op, DN: dn, Err: err}
+	var lerr *ldap.Error
+	if errors.As(err, &lerr) {
+		e.Code = lerr.ResultCode
+	}
+	return e
+}
+
+// ResultCode returns the LDAP result code of the error, or 0 if it has none.
+func ResultCode(err error) uint16 {
+	var opErr *OpError
+	if errors.As(err, &opErr) && opErr.Code != 0 {
+		return opErr.Code
+	}
+	var lerr *ldap.Error
+	if errors.As(err, &lerr) {
+		return lerr.ResultCode
+	}
+	return 0
+}
+
+// IsNotFound returns true if the error means the entry does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsAlreadyExists returns true if the error means the entry already exists.
+func IsAlreadyExists(err error) bool {
+	return errors.Is(err, ErrAlreadyExists)
+}
@@ -0,0 +1,114 @@
+package ldapx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapError(t *testing.T) {
+	assert.Nil(t, wrapError("add", "cn=test", nil))
+
+	err := wrapError("add", "cn=test", ldap.NewError(ldap.LDAPResultEntryAlreadyExists, errors.New("exists")))
+
+	var opErr *OpError
+	require.True(t, errors.As(err, &opErr))
+	assert.Equal(t, "add", opErr.Op)
+	assert.Equal(t, "cn=test", opErr.DN)
+	assert.EqualValues(t, ldap.LDAPResultEntryAlreadyExists, opErr.Code)
+	assert.True(t, errors.Is(err, ErrAlreadyExists))
+	assert.False(t, errors.Is(err, ErrNotFound))
+	assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists))
+	assert.EqualValues(t, ldap.LDAPResultEntryAlreadyExists, ResultCode(fmt.Errorf("context: %w", err)))
+
+	// Errors are only wrapped once.
+	assert.Same(t, err, wrapError("update", "cn=other", err))
+}
+
+func TestConn_ErrorTaxonomy(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=a,dc=example,dc=com", map[string][]string{"cn": {"a"}, "objectClass": {"person"}})
+	s.put("cn=b,dc=example,dc=com", map[string][]string{"cn": {"b"}, "objectClass": {"person"}})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.Add(NewAddRequest("cn=a,dc=example,dc=com", nil))
+	assert.True(t, IsAlreadyExists(err))
+	assert.Contains(t, err.Error(), "add cn=a,dc=example,dc=com")
+
+	err = conn.Del(NewDelRequest("cn=missing,dc=example,dc=com", nil))
+	assert.True(t, IsNotFound(err))
+
+	_, err = conn.FindEntry("cn=a,dc=example,dc=com", "(objectClass=person)", nil)
+	require.NoError(t, err)
+	s.put("dc=example,dc=com", map[string][]string{"dc": {"example"}})
+	_, err = conn.FindEntry("dc=example,dc=com", "(objectClass=person)", nil)
+	assert.True(t, errors.Is(err, ErrMultipleEntries))
+
+	s.setOverride(func(op ber.Tag, _ string) uint16 {
+		if op == ldap.ApplicationBindRequest {
+			return ldap.LDAPResultInvalidCredentials
+		}
+		return ldap.LDAPResultSuccess
+	})
+	err = conn.CheckBind("cn=a,dc=example,dc=com", "wrong")
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
+
+func TestEntry_UpdateTwice(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	entry := NewEntry("cn=test,dc=example,dc=com")
+	entry.AddAttributeValue("cn", "test")
+	require.NoError(t, entry.Update(conn))
+
+	err = entry.Update(conn)
+	assert.True(t, errors.Is(err, ErrAlreadyCommitted))
+}
+
+func TestConn_ExecuteWrapsErrors(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	del := func(lc *ldap.Conn) (interface{}, error) {
+		return nil, lc.Del(NewDelRequest("cn=missing,dc=example,dc=com", nil))
+	}
+	_, err = conn.ExecuteLdap(del)
+	var opErr *OpError
+	require.True(t, errors.As(err, &opErr))
+	assert.Equal(t, "execute", opErr.Op)
+	assert.True(t, IsNotFound(err))
+	assert.EqualValues(t, ldap.LDAPResultNoSuchObject, ResultCode(err))
+
+	_, err = conn.ExecuteAs("cn=a,dc=example,dc=com", "secret", del)
+	assert.True(t, IsNotFound(err))
+	assert.True(t, errors.As(err, &opErr))
+
+	// Errors of operations run inside Execute keep their own operation.
+	_, err = conn.Execute(func(tx *Conn) (interface{}, error) {
+		return nil, tx.Del(NewDelRequest("cn=missing,dc=example,dc=com", nil))
+	})
+	require.True(t, errors.As(err, &opErr))
+	assert.Equal(t, "delete", opErr.Op)
+	assert.True(t, IsNotFound(err))
+
+	_, err = conn.Execute(func(*Conn) (interface{}, error) {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("denied"))
+	})
+	assert.True(t, errors.Is(err, ErrInsufficientAccess))
+	assert.True(t, errors.As(err, &opErr))
+}
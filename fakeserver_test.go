@@ -0,0 +1,724 @@
+package ldapx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeServer is a minimal in-process LDAP server used by the unit tests. It
+// understands just enough of the protocol to bind, search, add, modify,
+// delete, rename and compare entries held in memory.
+type fakeServer struct {
+	t        *testing.T
+	listener net.Listener
+	mu       sync.Mutex
+	entries  map[string]map[string][]string // Entries keyed by lowercased DN
+	conns    map[net.Conn]struct{}
+	dials    atomic.Int32 // Number of accepted connections
+	binds    atomic.Int32 // Number of bind requests
+	searches atomic.Int32 // Number of search requests
+	writes   atomic.Int32 // Number of add, modify, delete and modify DN requests
+	delay    atomic.Int64 // Delay before answering each request, in nanoseconds
+	abandons atomic.Int32 // Number of paged searches abandoned with a page size of 0
+	lostAcks atomic.Int32 // Number of writes to apply without replying, closing the connection instead
+	bindLog  []string     // Identity of each bind, as "simple:<dn>" or "sasl:<mechanism>:<client cert CN>"
+
+	// tlsConfig, when set, enables the StartTLS extended operation.
+	tlsConfig *tls.Config
+
+	// override, when set, replaces the result code of any request. Searches
+	// are answered normally if it returns success.
+	override atomic.Pointer[func(op ber.Tag, dn string) uint16]
+}
+
+// setOverride sets the function that replaces result codes.
+func (s *fakeServer) setOverride(f func(op ber.Tag, dn string) uint16) {
+	s.override.Store(&f)
+}
+
+// overrideCode returns the overridden result code, and false if there is no override.
+func (s *fakeServer) overrideCode(op ber.Tag, dn string) (uint16, bool) {
+	f := s.override.Load()
+	if f == nil || *f == nil {
+		return 0, false
+	}
+	return (*f)(op, dn), true
+}
+
+// newFakeServer starts a fake server listening on a random local port.
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	s := &fakeServer{
+		t:        t,
+		listener: l,
+		entries:  make(map[string]map[string][]string),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.put("", map[string][]string{
+		"objectClass":       {"top"},
+		"subschemaSubentry": {"cn=subschema"},
+		"namingContexts":    {"dc=example,dc=com"},
+	})
+	s.put("cn=subschema", map[string][]string{
+		"objectClass":    {"subschema"},
+		"attributeTypes": {"( 2.5.4.3 NAME 'cn' )"},
+		"objectClasses":  {"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )"},
+	})
+
+	go s.serve()
+	t.Cleanup(s.stop)
+
+	return s
+}
+
+// url returns the ldap:// URL of the server.
+func (s *fakeServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// put stores an entry.
+func (s *fakeServer) put(dn string, attrs map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[strings.ToLower(dn)] = attrs
+}
+
+// get returns a stored entry.
+func (s *fakeServer) get(dn string) map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[strings.ToLower(dn)]
+}
+
+// parentExists returns false if an ancestor of the entry is stored but its
+// parent is not. Entries with no stored ancestor start a naming context.
+func (s *fakeServer) parentExists(dn string) bool {
+	parent := parentDN(strings.ToLower(dn))
+	if parent == "" || s.get(parent) != nil {
+		return true
+	}
+	for ancestor := parentDN(parent); ancestor != ""; ancestor = parentDN(ancestor) {
+		if s.get(ancestor) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// parentDN returns the DN of the parent of the entry, or "" for a DN of one
+// RDN. Escaped commas are not handled.
+func parentDN(dn string) string {
+	_, parent, _ := strings.Cut(dn, ",")
+	return parent
+}
+
+// stop closes the listener and all open connections.
+func (s *fakeServer) stop() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+}
+
+// dropConns closes all open connections but keeps listening.
+func (s *fakeServer) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+}
+
+func (s *fakeServer) serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.dials.Add(1)
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *fakeServer) handle(c net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		_ = c.Close()
+	}()
+
+	for {
+		p, err := ber.ReadPacket(c)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+		id := p.Children[0].Value.(int64)
+		req := p.Children[1]
+
+		if d := time.Duration(s.delay.Load()); d > 0 {
+			time.Sleep(d)
+		}
+
+		var out []*ber.Packet
+		switch req.Tag {
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationAbandonRequest:
+			continue
+		case ldap.ApplicationBindRequest:
+			s.binds.Add(1)
+			s.logBind(c, req)
+			out = append(out, s.response(ldap.ApplicationBindRequest, ldap.ApplicationBindResponse, req.Children[1].Data.String()))
+		case ldap.ApplicationSearchRequest:
+			s.searches.Add(1)
+			out = s.search(req, requestControls(p))
+		case ldap.ApplicationAddRequest:
+			s.writes.Add(1)
+			out = append(out, s.add(req))
+		case ldap.ApplicationModifyRequest:
+			s.writes.Add(1)
+			out = append(out, s.modify(req))
+		case ldap.ApplicationDelRequest:
+			s.writes.Add(1)
+			out = append(out, s.del(req))
+		case ldap.ApplicationModifyDNRequest:
+			s.writes.Add(1)
+			out = append(out, s.modifyDN(req))
+		case ldap.ApplicationCompareRequest:
+			out = append(out, s.compare(req))
+		case ldap.ApplicationExtendedRequest:
+			if req.Children[0].Data.String() != startTLSOID || s.tlsConfig == nil {
+				out = append(out, s.result(ldap.ApplicationExtendedResponse, "", ldap.LDAPResultProtocolError))
+				break
+			}
+			if !s.write(c, id, s.result(ldap.ApplicationExtendedResponse, "", ldap.LDAPResultSuccess), nil) {
+				return
+			}
+			c = tls.Server(c, s.tlsConfig)
+		default:
+			out = append(out, s.result(ldap.ApplicationExtendedResponse, "", ldap.LDAPResultProtocolError))
+		}
+
+		// The write is applied but the reply is lost with the connection.
+		if isWriteRequest(req.Tag) && s.loseAck() {
+			return
+		}
+
+		// A controls packet is sent with the message before it.
+		for i := 0; i < len(out); i++ {
+			op, controls := out[i], (*ber.Packet)(nil)
+			if i+1 < len(out) && out[i+1].ClassType == ber.ClassContext {
+				controls = out[i+1]
+				i++
+			}
+			if !s.write(c, id, op, controls) {
+				return
+			}
+		}
+	}
+}
+
+// isWriteRequest returns true if the request changes entries.
+func isWriteRequest(tag ber.Tag) bool {
+	switch tag {
+	case ldap.ApplicationAddRequest, ldap.ApplicationModifyRequest, ldap.ApplicationDelRequest, ldap.ApplicationModifyDNRequest:
+		return true
+	}
+	return false
+}
+
+// loseAck returns true if the reply to a write should be lost.
+func (s *fakeServer) loseAck() bool {
+	for {
+		n := s.lostAcks.Load()
+		if n <= 0 {
+			return false
+		}
+		if s.lostAcks.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+// logBind records the identity used by a bind request.
+func (s *fakeServer) logBind(c net.Conn, req *ber.Packet) {
+	entry := "simple:" + req.Children[1].Data.String()
+	if auth := req.Children[2]; auth.Tag == 3 {
+		entry = "sasl:" + auth.Children[0].Data.String() + ":"
+		if tc, ok := c.(*tls.Conn); ok && len(tc.ConnectionState().PeerCertificates) > 0 {
+			entry += tc.ConnectionState().PeerCertificates[0].Subject.CommonName
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bindLog = append(s.bindLog, entry)
+}
+
+// bindIdentities returns the recorded bind identities.
+func (s *fakeServer) bindIdentities() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bindLog...)
+}
+
+// write sends a response message.
+func (s *fakeServer) write(c net.Conn, id int64, op *ber.Packet, controls *ber.Packet) bool {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	envelope.AppendChild(op)
+	if controls != nil {
+		envelope.AppendChild(controls)
+	}
+	_, err := c.Write(envelope.Bytes())
+	return err == nil
+}
+
+// startTLSOID is the OID of the StartTLS extended operation.
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// enableTLS generates a self-signed certificate for 127.0.0.1 and enables
+// StartTLS. It returns a client configuration that trusts the certificate.
+func (s *fakeServer) enableTLS() *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		s.t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		s.t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		s.t.Fatalf("parse certificate: %v", err)
+	}
+
+	s.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		ClientAuth:   tls.RequestClientCert,
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &tls.Config{RootCAs: roots}
+}
+
+// clientCertificate generates a self-signed client certificate with the given common name.
+func (s *fakeServer) clientCertificate(cn string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		s.t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		s.t.Fatalf("create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// response builds a response to the given request, honouring the result override.
+func (s *fakeServer) response(req, resp ber.Tag, dn string) *ber.Packet {
+	code, ok := s.overrideCode(req, dn)
+	if !ok {
+		code = ldap.LDAPResultSuccess
+	}
+	return s.result(resp, "", code)
+}
+
+// result builds an LDAPResult packet.
+func (s *fakeServer) result(op ber.Tag, dn string, code uint16) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "matchedDN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return p
+}
+
+// search answers a search request. Entries are returned in DN order so that
+// paged results are stable; the paging cookie is the offset of the next entry.
+// Entries with a "ref" attribute are returned as search result references.
+func (s *fakeServer) search(req *ber.Packet, controls []ldap.Control) []*ber.Packet {
+	base := strings.ToLower(req.Children[0].Data.String())
+	scope := req.Children[1].Value.(int64)
+	filter := req.Children[6]
+	var wanted []string
+	for _, a := range req.Children[7].Children {
+		wanted = append(wanted, a.Data.String())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if code, ok := s.overrideCode(ldap.ApplicationSearchRequest, base); ok && code != ldap.LDAPResultSuccess {
+		return []*ber.Packet{s.result(ldap.ApplicationSearchResultDone, "", code)}
+	}
+
+	if _, ok := s.entries[base]; !ok {
+		return []*ber.Packet{s.result(ldap.ApplicationSearchResultDone, "", ldap.LDAPResultNoSuchObject)}
+	}
+
+	paging, _ := ldap.FindControl(controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+	offset := 0
+	if paging != nil && len(paging.Cookie) > 0 {
+		offset, _ = strconv.Atoi(string(paging.Cookie))
+		if paging.PagingSize == 0 {
+			s.abandons.Add(1)
+			return []*ber.Packet{s.result(ldap.ApplicationSearchResultDone, "", ldap.LDAPResultSuccess)}
+		}
+	}
+
+	var dns []string
+	for dn, attrs := range s.entries {
+		if inScope(dn, base, scope) && matchFilter(filter, attrs) {
+			dns = append(dns, dn)
+		}
+	}
+	sort.Strings(dns)
+
+	var out []*ber.Packet
+	next := ""
+	for i, dn := range dns[min(offset, len(dns)):] {
+		if paging != nil && paging.PagingSize > 0 && uint32(i) == paging.PagingSize {
+			next = strconv.Itoa(offset + i)
+			break
+		}
+		attrs := s.entries[dn]
+		if refs := lookupFold(attrs, "ref"); len(refs) > 0 {
+			p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultReference, nil, "Search Result Reference")
+			for _, ref := range refs {
+				p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, ref, "URI"))
+			}
+			out = append(out, p)
+			continue
+		}
+		p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+		p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "objectName"))
+		list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+		for name, values := range attrs {
+			if !wantAttribute(wanted, name) {
+				continue
+			}
+			a := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+			a.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+			}
+			a.AppendChild(set)
+			list.AppendChild(a)
+		}
+		p.AppendChild(list)
+		out = append(out, p)
+	}
+
+	done := s.result(ldap.ApplicationSearchResultDone, "", ldap.LDAPResultSuccess)
+	if paging != nil {
+		response := ldap.NewControlPaging(0)
+		response.SetCookie([]byte(next))
+		c := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		c.AppendChild(response.Encode())
+		return append(out, done, c)
+	}
+	return append(out, done)
+}
+
+// requestControls decodes the controls of a request message.
+func requestControls(p *ber.Packet) []ldap.Control {
+	if len(p.Children) < 3 {
+		return nil
+	}
+	var controls []ldap.Control
+	for _, c := range p.Children[2].Children {
+		if control, err := ldap.DecodeControl(c); err == nil {
+			controls = append(controls, control)
+		}
+	}
+	return controls
+}
+
+// inScope returns true if dn is within the search scope below base.
+func inScope(dn, base string, scope int64) bool {
+	switch {
+	case dn == base:
+		return scope != ldap.ScopeSingleLevel
+	case scope == ldap.ScopeBaseObject || dn == "":
+		return false
+	case base == "":
+		return scope == ldap.ScopeWholeSubtree || !strings.Contains(dn, ",")
+	case !strings.HasSuffix(dn, ","+base):
+		return false
+	case scope == ldap.ScopeSingleLevel:
+		return !strings.Contains(strings.TrimSuffix(dn, ","+base), ",")
+	}
+	return true
+}
+
+// wantAttribute returns true if the attribute was requested.
+func wantAttribute(wanted []string, name string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, w := range wanted {
+		if w == "*" || strings.EqualFold(w, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFilter evaluates the subset of filters the tests use. Unsupported
+// filter types match everything.
+func matchFilter(f *ber.Packet, attrs map[string][]string) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !matchFilter(c, attrs) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if matchFilter(c, attrs) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(f.Children[0], attrs)
+	case ldap.FilterPresent:
+		return len(lookupFold(attrs, f.Data.String())) > 0
+	case ldap.FilterEqualityMatch:
+		for _, v := range lookupFold(attrs, f.Children[0].Data.String()) {
+			if strings.EqualFold(v, f.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// lookupFold returns the values of an attribute, matching the name case-insensitively.
+func lookupFold(attrs map[string][]string, name string) []string {
+	for k, v := range attrs {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// add answers an add request.
+func (s *fakeServer) add(req *ber.Packet) *ber.Packet {
+	dn := req.Children[0].Data.String()
+	if s.override.Load() != nil {
+		return s.response(ldap.ApplicationAddRequest, ldap.ApplicationAddResponse, dn)
+	}
+	if s.get(dn) != nil {
+		return s.result(ldap.ApplicationAddResponse, "", ldap.LDAPResultEntryAlreadyExists)
+	}
+	if !s.parentExists(dn) {
+		return s.result(ldap.ApplicationAddResponse, "", ldap.LDAPResultNoSuchObject)
+	}
+	attrs := make(map[string][]string)
+	for _, a := range req.Children[1].Children {
+		attrs[a.Children[0].Data.String()] = packetValues(a.Children[1])
+	}
+	s.put(dn, attrs)
+	return s.result(ldap.ApplicationAddResponse, "", ldap.LDAPResultSuccess)
+}
+
+// modify answers a modify request.
+func (s *fakeServer) modify(req *ber.Packet) *ber.Packet {
+	dn := req.Children[0].Data.String()
+	if s.override.Load() != nil {
+		return s.response(ldap.ApplicationModifyRequest, ldap.ApplicationModifyResponse, dn)
+	}
+	attrs := s.get(dn)
+	if attrs == nil {
+		return s.result(ldap.ApplicationModifyResponse, "", ldap.LDAPResultNoSuchObject)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Changes are applied to a copy, so that a failed modify changes nothing.
+	changed := make(map[string][]string, len(attrs))
+	for k, v := range attrs {
+		changed[k] = v
+	}
+	for _, c := range req.Children[1].Children {
+		op := c.Children[0].Value.(int64)
+		name := c.Children[1].Children[0].Data.String()
+		values := packetValues(c.Children[1].Children[1])
+		for k := range changed {
+			if strings.EqualFold(k, name) {
+				name = k
+			}
+		}
+		switch op {
+		case ldap.AddAttribute:
+			changed[name] = append(append([]string(nil), changed[name]...), values...)
+		case ldap.ReplaceAttribute:
+			changed[name] = values
+		case ldap.DeleteAttribute:
+			if changed[name] == nil {
+				return s.result(ldap.ApplicationModifyResponse, "", ldap.LDAPResultNoSuchAttribute)
+			}
+			if len(values) == 0 {
+				delete(changed, name)
+				continue
+			}
+			var kept []string
+			for _, v := range values {
+				if !containsFold(changed[name], v) {
+					return s.result(ldap.ApplicationModifyResponse, "", ldap.LDAPResultNoSuchAttribute)
+				}
+			}
+			for _, v := range changed[name] {
+				if !containsFold(values, v) {
+					kept = append(kept, v)
+				}
+			}
+			changed[name] = kept
+		}
+		if len(changed[name]) == 0 {
+			delete(changed, name)
+		}
+	}
+	s.entries[strings.ToLower(dn)] = changed
+	return s.result(ldap.ApplicationModifyResponse, "", ldap.LDAPResultSuccess)
+}
+
+// del answers a delete request.
+func (s *fakeServer) del(req *ber.Packet) *ber.Packet {
+	dn := req.Data.String()
+	if s.override.Load() != nil {
+		return s.response(ldap.ApplicationDelRequest, ldap.ApplicationDelResponse, dn)
+	}
+	if s.get(dn) == nil {
+		return s.result(ldap.ApplicationDelResponse, "", ldap.LDAPResultNoSuchObject)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for other := range s.entries {
+		if parentDN(other) == strings.ToLower(dn) {
+			return s.result(ldap.ApplicationDelResponse, "", ldap.LDAPResultNotAllowedOnNonLeaf)
+		}
+	}
+	delete(s.entries, strings.ToLower(dn))
+	return s.result(ldap.ApplicationDelResponse, "", ldap.LDAPResultSuccess)
+}
+
+// modifyDN answers a modify DN request. The attributes of the entry are kept
+// as they are.
+func (s *fakeServer) modifyDN(req *ber.Packet) *ber.Packet {
+	dn := req.Children[0].Data.String()
+	if s.override.Load() != nil {
+		return s.response(ldap.ApplicationModifyDNRequest, ldap.ApplicationModifyDNResponse, dn)
+	}
+	attrs := s.get(dn)
+	if attrs == nil {
+		return s.result(ldap.ApplicationModifyDNResponse, "", ldap.LDAPResultNoSuchObject)
+	}
+
+	parent := ""
+	if _, p, ok := strings.Cut(dn, ","); ok {
+		parent = p
+	}
+	if len(req.Children) > 3 {
+		parent = req.Children[3].Data.String()
+	}
+	newDN := req.Children[1].Data.String()
+	if parent != "" {
+		newDN += "," + parent
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for other := range s.entries {
+		if parentDN(other) == strings.ToLower(dn) {
+			return s.result(ldap.ApplicationDelResponse, "", ldap.LDAPResultNotAllowedOnNonLeaf)
+		}
+	}
+	delete(s.entries, strings.ToLower(dn))
+	s.entries[strings.ToLower(newDN)] = attrs
+	return s.result(ldap.ApplicationModifyDNResponse, "", ldap.LDAPResultSuccess)
+}
+
+// compare answers a compare request.
+func (s *fakeServer) compare(req *ber.Packet) *ber.Packet {
+	dn := req.Children[0].Data.String()
+	attrs := s.get(dn)
+	if attrs == nil {
+		return s.result(ldap.ApplicationCompareResponse, "", ldap.LDAPResultNoSuchObject)
+	}
+	ava := req.Children[1]
+	if containsFold(lookupFold(attrs, ava.Children[0].Data.String()), ava.Children[1].Data.String()) {
+		return s.result(ldap.ApplicationCompareResponse, "", ldap.LDAPResultCompareTrue)
+	}
+	return s.result(ldap.ApplicationCompareResponse, "", ldap.LDAPResultCompareFalse)
+}
+
+// packetValues returns the values in a SET OF OCTET STRING.
+func packetValues(p *ber.Packet) []string {
+	values := make([]string, 0, len(p.Children))
+	for _, v := range p.Children {
+		values = append(values, v.Data.String())
+	}
+	return values
+}
+
+// containsFold returns true if values contains v, ignoring case.
+func containsFold(values []string, v string) bool {
+	for _, x := range values {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
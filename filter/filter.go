@@ -0,0 +1,356 @@
+// Package filter builds, parses and evaluates LDAP search filters, as
+// described in RFC 4515.
+//
+// Filters built with the functions of the package escape their values, so
+// input from users can be used safely:
+//
+//	f := filter.And(filter.Eq("objectClass", "person"), filter.Eq("uid", uid))
+//	entries, err := conn.QuickSearch(baseDN, f.String())
+//
+// Attribute descriptions and matching rules are not values and cannot be
+// escaped. If they come from users, check the filter with Validate. Those
+// that are not valid are written with hex pairs, so that they cannot change
+// the meaning of the filter and the server rejects it.
+//
+// Filters can also be evaluated against entries held in memory with Matches.
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jbirdman/ldapx"
+)
+
+// Filter is a search filter. The filters of the package are the nodes of the
+// tree returned by Parse.
+type Filter interface {
+	String() string              // String returns the string form of the filter
+	Matches(e *ldapx.Entry) bool // Matches returns true if the filter is true for the entry
+	eval(m *matcher) result      // eval evaluates the filter for the entry of the matcher
+	write(b *strings.Builder)    // write writes the string form of the filter
+}
+
+// AndFilter is true if all of its filters are true. With no filters it is
+// always true.
+type AndFilter struct {
+	Filters []Filter
+}
+
+// OrFilter is true if any of its filters is true. With no filters it is
+// always false.
+type OrFilter struct {
+	Filters []Filter
+}
+
+// NotFilter is true if its filter is false.
+type NotFilter struct {
+	Filter Filter
+}
+
+// EqualityFilter is true if the attribute has a value equal to Value.
+type EqualityFilter struct {
+	Attr  string
+	Value string
+}
+
+// SubstringsFilter is true if the attribute has a value that starts with
+// Initial, contains each of Any in order and ends with Final. Empty parts
+// are left out.
+type SubstringsFilter struct {
+	Attr    string
+	Initial string
+	Any     []string
+	Final   string
+}
+
+// GreaterOrEqualFilter is true if the attribute has a value greater than or
+// equal to Value.
+type GreaterOrEqualFilter struct {
+	Attr  string
+	Value string
+}
+
+// LessOrEqualFilter is true if the attribute has a value less than or equal
+// to Value.
+type LessOrEqualFilter struct {
+	Attr  string
+	Value string
+}
+
+// PresentFilter is true if the entry has the attribute.
+type PresentFilter struct {
+	Attr string
+}
+
+// ApproxFilter is true if the attribute has a value approximately equal to
+// Value.
+type ApproxFilter struct {
+	Attr  string
+	Value string
+}
+
+// ExtensibleFilter is true if a value matches Value under the matching rule.
+// Without an attribute, all attributes are tried; without a matching rule,
+// the equality matching rule of the attribute is used. If DNAttributes is
+// true, the attribute values of the DN of the entry are tried as well.
+type ExtensibleFilter struct {
+	Attr         string
+	MatchingRule string
+	DNAttributes bool
+	Value        string
+}
+
+// And returns a filter that is true if all of the filters are true.
+func And(filters ...Filter) Filter {
+	return &AndFilter{Filters: filters}
+}
+
+// Or returns a filter that is true if any of the filters is true.
+func Or(filters ...Filter) Filter {
+	return &OrFilter{Filters: filters}
+}
+
+// Not returns a filter that is true if the filter is false.
+func Not(filter Filter) Filter {
+	return &NotFilter{Filter: filter}
+}
+
+// Eq returns a filter that is true if the attribute has the value.
+func Eq(attr, value string) Filter {
+	return &EqualityFilter{Attr: attr, Value: value}
+}
+
+// Present returns a filter that is true if the entry has the attribute.
+func Present(attr string) Filter {
+	return &PresentFilter{Attr: attr}
+}
+
+// Substr returns a filter that is true if the attribute has a value that
+// starts with initial, contains each of middle in order and ends with final.
+// Empty strings match anything, so Substr("cn", "Jo", nil, "") matches the
+// values that start with "Jo".
+func Substr(attr, initial string, middle []string, final string) Filter {
+	return &SubstringsFilter{Attr: attr, Initial: initial, Any: middle, Final: final}
+}
+
+// GE returns a filter that is true if the attribute has a value greater than
+// or equal to the value.
+func GE(attr, value string) Filter {
+	return &GreaterOrEqualFilter{Attr: attr, Value: value}
+}
+
+// LE returns a filter that is true if the attribute has a value less than or
+// equal to the value.
+func LE(attr, value string) Filter {
+	return &LessOrEqualFilter{Attr: attr, Value: value}
+}
+
+// Approx returns a filter that is true if the attribute has a value
+// approximately equal to the value.
+func Approx(attr, value string) Filter {
+	return &ApproxFilter{Attr: attr, Value: value}
+}
+
+// Extensible returns a filter that is true if a value matches the value under
+// the matching rule. Either attr or rule may be empty, but not both.
+func Extensible(attr, rule string, dnAttributes bool, value string) Filter {
+	return &ExtensibleFilter{Attr: attr, MatchingRule: rule, DNAttributes: dnAttributes, Value: value}
+}
+
+// Escape escapes a value for use in the string form of a filter, as described
+// in RFC 4515. The special characters, NUL and bytes that are not valid UTF-8
+// are written as hex pairs.
+func Escape(value string) string {
+	var b strings.Builder
+	writeValue(&b, value)
+	return b.String()
+}
+
+// writeValue writes the escaped value.
+func writeValue(b *strings.Builder, value string) {
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRuneInString(value[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1,
+			r == '*', r == '(', r == ')', r == '\\', r == 0:
+			_, _ = fmt.Fprintf(b, "\\%02x", value[i])
+			size = 1
+		default:
+			b.WriteString(value[i : i+size])
+		}
+		i += size
+	}
+}
+
+// filterString returns the string form of the filter.
+func filterString(f Filter) string {
+	var b strings.Builder
+	f.write(&b)
+	return b.String()
+}
+
+func writeList(b *strings.Builder, op byte, filters []Filter) {
+	b.WriteByte('(')
+	b.WriteByte(op)
+	for _, f := range filters {
+		f.write(b)
+	}
+	b.WriteByte(')')
+}
+
+// writeAttr writes an attribute description or matching rule. The bytes of
+// one that is not valid are written as hex pairs, so it cannot end the item.
+func writeAttr(b *strings.Builder, attr string) {
+	for i := 0; i < len(attr); i++ {
+		if validAttr(attr[i : i+1]) {
+			b.WriteByte(attr[i])
+		} else {
+			_, _ = fmt.Fprintf(b, "\\%02x", attr[i])
+		}
+	}
+}
+
+func writeItem(b *strings.Builder, attr, op, value string) {
+	b.WriteByte('(')
+	writeAttr(b, attr)
+	b.WriteString(op)
+	writeValue(b, value)
+	b.WriteByte(')')
+}
+
+func (f *AndFilter) write(b *strings.Builder) { writeList(b, '&', f.Filters) }
+func (f *OrFilter) write(b *strings.Builder)  { writeList(b, '|', f.Filters) }
+
+func (f *NotFilter) write(b *strings.Builder) {
+	b.WriteString("(!")
+	f.Filter.write(b)
+	b.WriteByte(')')
+}
+
+func (f *EqualityFilter) write(b *strings.Builder)       { writeItem(b, f.Attr, "=", f.Value) }
+func (f *GreaterOrEqualFilter) write(b *strings.Builder) { writeItem(b, f.Attr, ">=", f.Value) }
+func (f *LessOrEqualFilter) write(b *strings.Builder)    { writeItem(b, f.Attr, "<=", f.Value) }
+func (f *ApproxFilter) write(b *strings.Builder)         { writeItem(b, f.Attr, "~=", f.Value) }
+
+func (f *PresentFilter) write(b *strings.Builder) {
+	b.WriteByte('(')
+	writeAttr(b, f.Attr)
+	b.WriteString("=*)")
+}
+
+func (f *SubstringsFilter) write(b *strings.Builder) {
+	b.WriteByte('(')
+	writeAttr(b, f.Attr)
+	b.WriteByte('=')
+	writeValue(b, f.Initial)
+	b.WriteByte('*')
+	for _, s := range f.Any {
+		if s == "" {
+			continue
+		}
+		writeValue(b, s)
+		b.WriteByte('*')
+	}
+	writeValue(b, f.Final)
+	b.WriteByte(')')
+}
+
+func (f *ExtensibleFilter) write(b *strings.Builder) {
+	b.WriteByte('(')
+	if f.Attr != "" {
+		writeAttr(b, f.Attr)
+	}
+	if f.DNAttributes {
+		b.WriteString(":dn")
+	}
+	if f.MatchingRule != "" {
+		b.WriteByte(':')
+		writeAttr(b, f.MatchingRule)
+	}
+	b.WriteString(":=")
+	writeValue(b, f.Value)
+	b.WriteByte(')')
+}
+
+// Validate returns an error if an attribute description or matching rule of
+// the filter is not valid, as described in RFC 4515. Parse checks the same.
+func Validate(f Filter) error {
+	switch f := f.(type) {
+	case *AndFilter:
+		return validateList(f.Filters)
+	case *OrFilter:
+		return validateList(f.Filters)
+	case *NotFilter:
+		return Validate(f.Filter)
+	case *EqualityFilter:
+		return validateAttr(f.Attr)
+	case *SubstringsFilter:
+		return validateAttr(f.Attr)
+	case *GreaterOrEqualFilter:
+		return validateAttr(f.Attr)
+	case *LessOrEqualFilter:
+		return validateAttr(f.Attr)
+	case *PresentFilter:
+		return validateAttr(f.Attr)
+	case *ApproxFilter:
+		return validateAttr(f.Attr)
+	case *ExtensibleFilter:
+		switch {
+		case f.Attr == "" && f.MatchingRule == "":
+			return fmt.Errorf("filter: extensible match needs an attribute or a matching rule")
+		case f.Attr != "" && !validAttr(f.Attr):
+			return fmt.Errorf("filter: invalid attribute description %q", f.Attr)
+		case f.MatchingRule != "" && !validAttr(f.MatchingRule):
+			return fmt.Errorf("filter: invalid matching rule %q", f.MatchingRule)
+		}
+	}
+	return nil
+}
+
+func validateList(filters []Filter) error {
+	for _, f := range filters {
+		if err := Validate(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAttr(attr string) error {
+	if !validAttr(attr) {
+		return fmt.Errorf("filter: invalid attribute description %q", attr)
+	}
+	return nil
+}
+
+// String returns the string form of the filter.
+func (f *AndFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *OrFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *NotFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *EqualityFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *SubstringsFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *GreaterOrEqualFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *LessOrEqualFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *PresentFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *ApproxFilter) String() string { return filterString(f) }
+
+// String returns the string form of the filter.
+func (f *ExtensibleFilter) String() string { return filterString(f) }
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"john", "john"},
+		{"*)(uid=*", `\2a\29\28uid=\2a`},
+		{`a\b`, `a\5cb`},
+		{"nul\x00", `nul\00`},
+		{"café", "café"},
+		{"\xff\xfe", `\ff\fe`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Escape(tt.value))
+	}
+}
+
+func TestFilter_String(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		want   string
+	}{
+		{Eq("uid", "*)(uid=*"), `(uid=\2a\29\28uid=\2a)`},
+		{Present("mail"), "(mail=*)"},
+		{Substr("cn", "Jo(", nil, ""), `(cn=Jo\28*)`},
+		{Substr("cn", "", []string{"a*", "", "b"}, "c"), `(cn=*a\2a*b*c)`},
+		{GE("uidNumber", "1000"), "(uidNumber>=1000)"},
+		{LE("uidNumber", "2000"), "(uidNumber<=2000)"},
+		{Approx("sn", "smith"), "(sn~=smith)"},
+		{Extensible("cn", "caseExactMatch", true, "Fred"), "(cn:dn:caseExactMatch:=Fred)"},
+		{Extensible("", "2.5.13.2", false, "x"), "(:2.5.13.2:=x)"},
+		{And(), "(&)"},
+		{Or(), "(|)"},
+		{
+			And(Eq("objectClass", "person"), Or(Eq("uid", "a"), Not(Present("mail")))),
+			"(&(objectClass=person)(|(uid=a)(!(mail=*))))",
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.filter.String())
+	}
+}
+
+func TestFilter_InjectedAttribute(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		want   string
+	}{
+		{Eq("cn)(objectClass=*", "x"), `(cn\29\28objectClass\3d\2a=x)`},
+		{Present("cn=*)(uid"), `(cn\3d\2a\29\28uid=*)`},
+		{Substr("cn)(uid", "a", nil, ""), `(cn\29\28uid=a*)`},
+		{GE("cn>", "x"), `(cn\3e>=x)`},
+		{Extensible("cn", "rule)(uid=*", false, "x"), `(cn:rule\29\28uid\3d\2a:=x)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Error(t, Validate(tt.filter))
+
+			// The attribute cannot close the item and start another, so the
+			// filter is rejected rather than read with another meaning.
+			s := And(tt.filter).String()
+			assert.Equal(t, "(&"+tt.want+")", s)
+			_, err := Parse(s)
+			assert.Error(t, err)
+		})
+	}
+
+	require.NoError(t, Validate(And(Eq("cn;lang-en", "x"), Not(Extensible("", "2.5.13.2", true, "y")))))
+	assert.Error(t, Validate(Or(Present("mail"), Extensible("", "", false, "x"))))
+}
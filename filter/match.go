@@ -0,0 +1,457 @@
+package filter
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/jbirdman/ldapx"
+)
+
+// Filters are evaluated as the server evaluates them, as described in RFC
+// 4511: a filter item is undefined if its attribute or matching rule is not
+// known, and "not" of an undefined filter is undefined. Only true filters
+// match.
+//
+// Values are compared with the matching rules of the schema the entry is
+// bound to. Without a schema, values are compared ignoring case and spaces,
+// as most attributes in common use are, and ordering compares integers by
+// value. Approximate matching is done as equality matching.
+
+// result is the outcome of evaluating a filter.
+type result int
+
+const (
+	resultFalse     result = iota // resultFalse is the outcome of a false filter
+	resultTrue                    // resultTrue is the outcome of a true filter
+	resultUndefined               // resultUndefined is the outcome of a filter that cannot be evaluated
+)
+
+// resultOf converts a boolean to a result.
+func resultOf(b bool) result {
+	if b {
+		return resultTrue
+	}
+	return resultFalse
+}
+
+// matcher evaluates filters for an entry.
+type matcher struct {
+	entry  *ldapx.Entry
+	schema *ldapx.LDAPSchema
+}
+
+// matches returns true if the filter is true for the entry.
+func matches(f Filter, e *ldapx.Entry) bool {
+	return f.eval(&matcher{entry: e, schema: e.Schema()}) == resultTrue
+}
+
+// Matches returns true if the filter is true for the entry.
+func (f *AndFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *OrFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *NotFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *EqualityFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *SubstringsFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *GreaterOrEqualFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *LessOrEqualFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *PresentFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *ApproxFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+// Matches returns true if the filter is true for the entry.
+func (f *ExtensibleFilter) Matches(e *ldapx.Entry) bool { return matches(f, e) }
+
+func (f *AndFilter) eval(m *matcher) result {
+	r := resultTrue
+	for _, f := range f.Filters {
+		switch f.eval(m) {
+		case resultFalse:
+			return resultFalse
+		case resultUndefined:
+			r = resultUndefined
+		}
+	}
+	return r
+}
+
+func (f *OrFilter) eval(m *matcher) result {
+	r := resultFalse
+	for _, f := range f.Filters {
+		switch f.eval(m) {
+		case resultTrue:
+			return resultTrue
+		case resultUndefined:
+			r = resultUndefined
+		}
+	}
+	return r
+}
+
+func (f *NotFilter) eval(m *matcher) result {
+	switch f.Filter.eval(m) {
+	case resultTrue:
+		return resultFalse
+	case resultFalse:
+		return resultTrue
+	}
+	return resultUndefined
+}
+
+func (f *EqualityFilter) eval(m *matcher) result {
+	return m.equal(f.Attr, f.Value)
+}
+
+func (f *ApproxFilter) eval(m *matcher) result {
+	return m.equal(f.Attr, f.Value)
+}
+
+func (f *PresentFilter) eval(m *matcher) result {
+	return resultOf(len(m.values(f.Attr)) > 0)
+}
+
+func (f *SubstringsFilter) eval(m *matcher) result {
+	n, ok := m.substrings(f.Attr)
+	if !ok {
+		return resultUndefined
+	}
+	initial, final := n(f.Initial), n(f.Final)
+	middle := make([]string, 0, len(f.Any))
+	for _, s := range f.Any {
+		middle = append(middle, n(s))
+	}
+	for _, v := range m.values(f.Attr) {
+		if substringsMatch(n(v), initial, middle, final) {
+			return resultTrue
+		}
+	}
+	return resultFalse
+}
+
+func (f *GreaterOrEqualFilter) eval(m *matcher) result {
+	return m.order(f.Attr, f.Value, func(c int) bool { return c >= 0 })
+}
+
+func (f *LessOrEqualFilter) eval(m *matcher) result {
+	return m.order(f.Attr, f.Value, func(c int) bool { return c <= 0 })
+}
+
+func (f *ExtensibleFilter) eval(m *matcher) result {
+	var n func(string) string
+	switch {
+	case f.MatchingRule != "":
+		n = m.ruleNormalizer(f.MatchingRule)
+	case f.Attr != "":
+		n, _ = m.equality(f.Attr)
+	}
+	if n == nil {
+		return resultUndefined
+	}
+
+	var values []string
+	if f.Attr != "" {
+		values = m.values(f.Attr)
+	} else {
+		for _, name := range m.entry.AttributeNames() {
+			values = append(values, m.entry.GetAttributeValues(name)...)
+		}
+	}
+	if f.DNAttributes {
+		values = append(values, m.dnValues(f.Attr)...)
+	}
+
+	want := n(f.Value)
+	for _, v := range values {
+		if n(v) == want {
+			return resultTrue
+		}
+	}
+	return resultFalse
+}
+
+// equal evaluates an equality filter.
+func (m *matcher) equal(attr, value string) result {
+	n, ok := m.equality(attr)
+	if !ok {
+		return resultUndefined
+	}
+	want := n(value)
+	for _, v := range m.values(attr) {
+		if n(v) == want {
+			return resultTrue
+		}
+	}
+	return resultFalse
+}
+
+// order evaluates an ordering filter: it is true if ok is true for the
+// comparison of a value with the assertion value.
+func (m *matcher) order(attr, value string, ok func(int) bool) result {
+	cmp := m.ordering(attr)
+	if cmp == nil {
+		return resultUndefined
+	}
+	if _, valid := cmp(value, value); !valid {
+		return resultUndefined
+	}
+	for _, v := range m.values(attr) {
+		if c, valid := cmp(v, value); valid && ok(c) {
+			return resultTrue
+		}
+	}
+	return resultFalse
+}
+
+// values returns the values of the attributes of the entry that the attribute
+// description names: those of the same type with at least the same options.
+func (m *matcher) values(desc string) []string {
+	typ, options, _ := strings.Cut(desc, ";")
+	var values []string
+	for _, name := range m.entry.AttributeNames() {
+		t, o, _ := strings.Cut(name, ";")
+		if m.sameType(typ, t) && hasOptions(o, options) {
+			values = append(values, m.entry.GetAttributeValues(name)...)
+		}
+	}
+	return values
+}
+
+// dnValues returns the values of the RDNs of the entry DN of the attribute,
+// or of all attributes if attr is empty.
+func (m *matcher) dnValues(attr string) []string {
+	dn, err := ldapx.ParseDN(m.entry.DN)
+	if err != nil {
+		return nil
+	}
+	var values []string
+	for _, rdn := range dn.RDNs {
+		for _, a := range rdn.Attributes {
+			if attr == "" || m.sameType(attr, a.Type) {
+				values = append(values, a.Value)
+			}
+		}
+	}
+	return values
+}
+
+// sameType returns true if the names are those of the same attribute type.
+func (m *matcher) sameType(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	if m.schema == nil {
+		return false
+	}
+	at := m.schema.AttributeType(a)
+	return at != nil && at == m.schema.AttributeType(b)
+}
+
+// hasOptions returns true if the options, separated by semicolons, include
+// all of want. Case is ignored.
+func hasOptions(options, want string) bool {
+	if want == "" {
+		return true
+	}
+	have := strings.Split(strings.ToLower(options), ";")
+	for _, o := range strings.Split(strings.ToLower(want), ";") {
+		found := false
+		for _, h := range have {
+			if h == o {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// caseIgnore is the normalizer used without a schema.
+func caseIgnore(v string) string {
+	n, _ := ldapx.NormalizeValueByRule("caseIgnoreMatch", v)
+	return n
+}
+
+// equality returns the normalizer of the equality matching rule of the
+// attribute. It returns false if the attribute type or its rule is not in
+// the schema.
+func (m *matcher) equality(attr string) (func(string) string, bool) {
+	if m.schema == nil {
+		return caseIgnore, true
+	}
+	at := m.schema.AttributeType(attr)
+	if at == nil || at.Equality == "" {
+		return nil, false
+	}
+	return func(v string) string {
+		return m.schema.NormalizeValue(attr, v)
+	}, true
+}
+
+// substringsRules maps the substrings matching rules, by lowercased name and
+// OID, to the equality matching rules that normalize their values the same
+// way.
+var substringsRules = map[string]string{
+	"caseignoresubstringsmatch":      "caseIgnoreMatch",
+	"2.5.13.4":                       "caseIgnoreMatch",
+	"caseexactsubstringsmatch":       "caseExactMatch",
+	"2.5.13.7":                       "caseExactMatch",
+	"caseignoreia5substringsmatch":   "caseIgnoreIA5Match",
+	"1.3.6.1.4.1.4203.1.2.1":         "caseIgnoreIA5Match",
+	"caseignorelistsubstringsmatch":  "caseIgnoreListMatch",
+	"2.5.13.12":                      "caseIgnoreListMatch",
+	"numericstringsubstringsmatch":   "numericStringMatch",
+	"2.5.13.10":                      "numericStringMatch",
+	"telephonenumbersubstringsmatch": "telephoneNumberMatch",
+	"2.5.13.21":                      "telephoneNumberMatch",
+	"octetstringsubstringsmatch":     "octetStringMatch",
+	"2.5.13.19":                      "octetStringMatch",
+}
+
+// substrings returns the normalizer of the substrings matching rule of the
+// attribute. It returns false if the attribute type or its rule is not
+// known.
+func (m *matcher) substrings(attr string) (func(string) string, bool) {
+	if m.schema == nil {
+		return caseIgnore, true
+	}
+	at := m.schema.AttributeType(attr)
+	if at == nil {
+		return nil, false
+	}
+	rule, ok := substringsRules[strings.ToLower(at.Substr)]
+	if !ok {
+		return nil, false
+	}
+	return func(v string) string {
+		n, _ := ldapx.NormalizeValueByRule(rule, v)
+		return n
+	}, true
+}
+
+// substringsMatch returns true if the value starts with initial, contains
+// each of middle in order and ends with final.
+func substringsMatch(v, initial string, middle []string, final string) bool {
+	if !strings.HasPrefix(v, initial) {
+		return false
+	}
+	v = v[len(initial):]
+	for _, s := range middle {
+		i := strings.Index(v, s)
+		if i < 0 {
+			return false
+		}
+		v = v[i+len(s):]
+	}
+	return strings.HasSuffix(v, final)
+}
+
+// ruleNormalizer returns the normalizer of the equality matching rule given by
+// name or OID, or nil if it is not known.
+func (m *matcher) ruleNormalizer(rule string) func(string) string {
+	if _, ok := ldapx.NormalizeValueByRule(rule, ""); !ok {
+		if m.schema == nil {
+			return nil
+		}
+		mr := m.schema.MatchingRule(rule)
+		if mr == nil {
+			return nil
+		}
+		if _, ok := ldapx.NormalizeValueByRule(mr.OID, ""); !ok {
+			return nil
+		}
+		rule = mr.OID
+	}
+	return func(v string) string {
+		n, _ := ldapx.NormalizeValueByRule(rule, v)
+		return n
+	}
+}
+
+// comparer compares two values; it returns false if either cannot be
+// compared.
+type comparer func(a, b string) (int, bool)
+
+// ordering returns the comparer of the ordering matching rule of the
+// attribute, or nil if the attribute type or its rule is not known.
+func (m *matcher) ordering(attr string) comparer {
+	if m.schema == nil {
+		return compareDefault
+	}
+	at := m.schema.AttributeType(attr)
+	if at == nil {
+		return nil
+	}
+	switch strings.ToLower(at.Ordering) {
+	case "integerorderingmatch", "2.5.13.15":
+		return compareIntegers
+	case "generalizedtimeorderingmatch", "2.5.13.28":
+		return compareGeneralizedTimes
+	case "caseignoreorderingmatch", "2.5.13.3":
+		return compareNormalized("caseIgnoreMatch")
+	case "caseexactorderingmatch", "2.5.13.6":
+		return compareNormalized("caseExactMatch")
+	case "numericstringorderingmatch", "2.5.13.9":
+		return compareNormalized("numericStringMatch")
+	case "octetstringorderingmatch", "2.5.13.18":
+		return compareNormalized("octetStringMatch")
+	}
+	return nil
+}
+
+// compareDefault compares integers by value and other values ignoring case.
+func compareDefault(a, b string) (int, bool) {
+	if c, ok := compareIntegers(a, b); ok {
+		return c, true
+	}
+	return strings.Compare(caseIgnore(a), caseIgnore(b)), true
+}
+
+func compareIntegers(a, b string) (int, bool) {
+	x, ok := new(big.Int).SetString(strings.TrimSpace(a), 10)
+	if !ok {
+		return 0, false
+	}
+	y, ok := new(big.Int).SetString(strings.TrimSpace(b), 10)
+	if !ok {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+func compareGeneralizedTimes(a, b string) (int, bool) {
+	x, err := ldapx.ParseGeneralizedTime(a)
+	if err != nil {
+		return 0, false
+	}
+	y, err := ldapx.ParseGeneralizedTime(b)
+	if err != nil {
+		return 0, false
+	}
+	return x.Compare(y), true
+}
+
+// compareNormalized returns a comparer of the values normalized by the
+// equality matching rule.
+func compareNormalized(rule string) comparer {
+	return func(a, b string) (int, bool) {
+		x, _ := ldapx.NormalizeValueByRule(rule, a)
+		y, _ := ldapx.NormalizeValueByRule(rule, b)
+		return strings.Compare(x, y), true
+	}
+}
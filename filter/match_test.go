@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jbirdman/ldapx"
+)
+
+func testSchema() *ldapx.LDAPSchema {
+	return ldapx.NewLDAPSchemaFromLdapEntry(ldap.NewEntry("cn=schema", map[string][]string{
+		"attributeTypes": {
+			"( 2.5.4.0 NAME 'objectClass' EQUALITY objectIdentifierMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.38 )",
+			"( 2.5.4.3 NAME ( 'cn' 'commonName' ) EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )",
+			"( 0.9.2342.19200300.100.1.1 NAME 'uid' EQUALITY caseIgnoreMatch SUBSTR caseIgnoreSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )",
+			"( 1.3.6.1.1.1.1.0 NAME 'uidNumber' EQUALITY integerMatch ORDERING integerOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )",
+			"( 2.5.4.35 NAME 'userPassword' EQUALITY octetStringMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.40 )",
+			"( 2.5.4.31 NAME 'member' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 )",
+			"( 2.5.18.1 NAME 'createTimestamp' EQUALITY generalizedTimeMatch ORDERING generalizedTimeOrderingMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.24 )",
+		},
+		"objectClasses": {
+			"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) )",
+		},
+	}))
+}
+
+func testEntry() *ldapx.Entry {
+	return ldapx.NewEntryFromLdapEntry(ldap.NewEntry("uid=jsmith,ou=People,dc=example,dc=com", map[string][]string{
+		"objectClass":     {"top", "person"},
+		"cn":              {"John  Smith", "Johnny"},
+		"cn;lang-fr":      {"Jean Smith"},
+		"uid":             {"jsmith"},
+		"uidNumber":       {"1005"},
+		"userPassword":    {"Secret"},
+		"member":          {"cn=Admins, ou=Groups,dc=example,dc=com"},
+		"createTimestamp": {"20240102030405Z"},
+	}))
+}
+
+func TestFilter_Matches(t *testing.T) {
+	tests := []struct {
+		filter          string
+		noSchema, bound bool
+	}{
+		{"(cn=john smith)", true, true},
+		{"(CommonName=john smith)", false, true},
+		{"(cn=Jean Smith)", true, true},
+		{"(cn;lang-fr=Johnny)", false, false},
+		{"(cn;lang-fr=jean smith)", true, true},
+		{"(cn=Bob)", false, false},
+		{"(mail=*)", false, false},
+		{"(uid=*)", true, true},
+		{"(cn=jo*)", true, true},
+		{"(cn=*SMITH)", true, true},
+		{"(cn=j*n*s*h)", true, true},
+		{"(cn=j*s*n)", false, false},
+		{"(uidNumber>=1000)", true, true},
+		{"(uidNumber>=999)", true, true},
+		{"(uidNumber<=999)", false, false},
+		{"(uidNumber=+1005)", false, true},
+		{"(uidNumber>=abc)", false, false},
+		{"(createTimestamp<=20240102040405+0100)", true, true},
+		{"(createTimestamp<=20240102033405+0100)", true, false},
+		{"(userPassword=secret)", true, false},
+		{"(userPassword=Secret)", true, true},
+		{"(objectClass=2.5.6.6)", false, true},
+		{"(member=cn=admins,ou=groups,dc=example,dc=com)", false, true},
+		{"(sn~=smith)", false, false},
+		{"(cn~=JOHNNY)", true, true},
+		{"(uid:caseExactMatch:=JSmith)", false, false},
+		{"(uid:caseIgnoreMatch:=JSmith)", true, true},
+		{"(ou:dn:=people)", true, false},
+		{"(:dn:2.5.13.2:=PEOPLE)", true, true},
+		{"(:unknownMatch:=x)", false, false},
+		{"(&(objectClass=person)(|(uid=other)(uidNumber>=1000)))", true, true},
+		{"(&(objectClass=person)(!(uid=jsmith)))", false, false},
+		{"(&)", true, true},
+		{"(|)", false, false},
+	}
+
+	s := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := Parse(tt.filter)
+			require.NoError(t, err)
+
+			e := testEntry()
+			assert.Equal(t, tt.noSchema, f.Matches(e), "without schema")
+			assert.Equal(t, tt.bound, f.Matches(e.BindSchema(s)), "with schema")
+		})
+	}
+}
+
+func TestFilter_MatchesUndefined(t *testing.T) {
+	e := testEntry().BindSchema(testSchema())
+
+	// An attribute the schema does not know is undefined, and so is its
+	// negation.
+	assert.False(t, Eq("unknown", "x").Matches(e))
+	assert.False(t, Not(Eq("unknown", "x")).Matches(e))
+	assert.True(t, Not(Eq("cn", "x")).Matches(e))
+
+	// Undefined is false in an or filter that has a true filter and true in
+	// an and filter that has a false filter, once negated.
+	assert.True(t, Or(Eq("unknown", "x"), Eq("uid", "jsmith")).Matches(e))
+	assert.True(t, Not(And(Eq("unknown", "x"), Eq("uid", "other"))).Matches(e))
+	assert.False(t, Not(And(Eq("unknown", "x"), Eq("uid", "jsmith"))).Matches(e))
+
+	// userPassword has no substrings or ordering rule.
+	assert.False(t, Substr("userPassword", "S", nil, "").Matches(e))
+	assert.False(t, Not(Substr("userPassword", "S", nil, "")).Matches(e))
+	assert.False(t, Not(GE("userPassword", "A")).Matches(e))
+}
+
+func TestFilter_MatchesBuilt(t *testing.T) {
+	e := testEntry()
+
+	// Values that look like filter syntax are matched as they are.
+	assert.False(t, Eq("uid", "*)(uid=*").Matches(e))
+	e.AddAttributeValue("description", "*)(uid=*")
+	assert.True(t, Eq("description", "*)(uid=*").Matches(e))
+
+	f, err := Parse(Eq("description", "*)(uid=*").String())
+	require.NoError(t, err)
+	assert.True(t, f.Matches(e))
+}
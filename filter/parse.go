@@ -0,0 +1,319 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDepth is the deepest nesting of filters that Parse accepts.
+const maxDepth = 100
+
+// SyntaxError is returned by Parse for a filter that is not valid.
+type SyntaxError struct {
+	Offset int    // Offset of the byte the error was found at
+	Msg    string // Description of the error
+}
+
+// Error returns the error message.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: offset %d: %s", e.Offset, e.Msg)
+}
+
+// Parse parses the string form of a filter, as described in RFC 4515. Spaces
+// are allowed around the filter and between the filters of a list. The
+// error, if any, is a *SyntaxError.
+func Parse(s string) (Filter, error) {
+	p := &parser{s: s}
+	p.skipSpaces()
+	f, err := p.parseFilter(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q after filter", p.s[p.pos])
+	}
+	return f, nil
+}
+
+// parser holds the state of Parse.
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// expect consumes the byte c.
+func (p *parser) expect(c byte) error {
+	if p.pos >= len(p.s) {
+		return p.errorf("expected %q, found end of filter", c)
+	}
+	if p.s[p.pos] != c {
+		return p.errorf("expected %q, found %q", c, p.s[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+// parseFilter parses a parenthesized filter.
+func (p *parser) parseFilter(depth int) (Filter, error) {
+	if depth > maxDepth {
+		return nil, p.errorf("filter nested too deeply")
+	}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.s) {
+		return nil, p.errorf("unexpected end of filter")
+	}
+
+	var f Filter
+	var err error
+	switch p.s[p.pos] {
+	case '&':
+		p.pos++
+		var filters []Filter
+		filters, err = p.parseList(depth)
+		f = &AndFilter{Filters: filters}
+	case '|':
+		p.pos++
+		var filters []Filter
+		filters, err = p.parseList(depth)
+		f = &OrFilter{Filters: filters}
+	case '!':
+		p.pos++
+		p.skipSpaces()
+		var not Filter
+		not, err = p.parseFilter(depth + 1)
+		p.skipSpaces()
+		f = &NotFilter{Filter: not}
+	default:
+		f, err = p.parseItem()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseList parses the filters of an and or an or filter, up to the closing
+// parenthesis. The list may be empty, as described in RFC 4526.
+func (p *parser) parseList(depth int) ([]Filter, error) {
+	var filters []Filter
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) || p.s[p.pos] != '(' {
+			return filters, nil
+		}
+		f, err := p.parseFilter(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+}
+
+// parseItem parses a filter that is not a list, up to the closing
+// parenthesis.
+func (p *parser) parseItem() (Filter, error) {
+	start := p.pos
+	eq := strings.IndexAny(p.s[start:], "=()")
+	if eq < 0 || p.s[start+eq] != '=' {
+		return nil, p.errorf("missing '=' in filter item")
+	}
+	eq += start
+	desc := p.s[start:eq]
+
+	var kind byte
+	if len(desc) > 0 {
+		switch c := desc[len(desc)-1]; c {
+		case '~', '>', '<', ':':
+			kind = c
+			desc = desc[:len(desc)-1]
+		}
+	}
+
+	p.pos = eq + 1
+	valueStart := p.pos
+	end := strings.IndexAny(p.s[valueStart:], "()")
+	if end < 0 {
+		p.pos = len(p.s)
+		return nil, p.errorf("missing ')' after value")
+	}
+	if p.s[valueStart+end] == '(' {
+		p.pos = valueStart + end
+		return nil, p.errorf("unescaped '(' in value")
+	}
+	raw := p.s[valueStart : valueStart+end]
+
+	if kind == ':' {
+		f, err := p.parseExtensible(start, desc)
+		if err != nil {
+			return nil, err
+		}
+		if f.Value, err = p.unescape(raw, valueStart); err != nil {
+			return nil, err
+		}
+		p.pos = valueStart + end
+		return f, nil
+	}
+
+	if !validAttr(desc) {
+		p.pos = start
+		return nil, p.errorf("invalid attribute description %q", desc)
+	}
+
+	var f Filter
+	if kind == 0 && raw == "*" {
+		f = &PresentFilter{Attr: desc}
+	} else if kind == 0 && strings.Contains(raw, "*") {
+		s, err := p.parseSubstrings(desc, raw, valueStart)
+		if err != nil {
+			return nil, err
+		}
+		f = s
+	} else {
+		value, err := p.unescape(raw, valueStart)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case '~':
+			f = &ApproxFilter{Attr: desc, Value: value}
+		case '>':
+			f = &GreaterOrEqualFilter{Attr: desc, Value: value}
+		case '<':
+			f = &LessOrEqualFilter{Attr: desc, Value: value}
+		default:
+			f = &EqualityFilter{Attr: desc, Value: value}
+		}
+	}
+	p.pos = valueStart + end
+	return f, nil
+}
+
+// parseSubstrings parses the value of a substrings filter.
+func (p *parser) parseSubstrings(attr, raw string, offset int) (*SubstringsFilter, error) {
+	f := &SubstringsFilter{Attr: attr}
+	parts := strings.Split(raw, "*")
+	for i, part := range parts {
+		value, err := p.unescape(part, offset)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case i == 0:
+			f.Initial = value
+		case i == len(parts)-1:
+			f.Final = value
+		case value != "":
+			f.Any = append(f.Any, value)
+		}
+		offset += len(part) + 1
+	}
+	return f, nil
+}
+
+// parseExtensible parses the attribute description, dn flag and matching
+// rule of an extensible filter, given without the trailing ':'.
+func (p *parser) parseExtensible(start int, desc string) (*ExtensibleFilter, error) {
+	parts := strings.Split(desc, ":")
+	f := &ExtensibleFilter{Attr: parts[0]}
+	parts = parts[1:]
+	if len(parts) > 0 && strings.EqualFold(parts[0], "dn") {
+		f.DNAttributes = true
+		parts = parts[1:]
+	}
+	if len(parts) > 0 {
+		f.MatchingRule = parts[0]
+		parts = parts[1:]
+	}
+
+	p.pos = start
+	switch {
+	case len(parts) > 0:
+		return nil, p.errorf("invalid extensible match %q", desc)
+	case f.Attr == "" && f.MatchingRule == "":
+		return nil, p.errorf("extensible match needs an attribute or a matching rule")
+	case f.Attr != "" && !validAttr(f.Attr):
+		return nil, p.errorf("invalid attribute description %q", f.Attr)
+	case f.MatchingRule != "" && !validAttr(f.MatchingRule):
+		return nil, p.errorf("invalid matching rule %q", f.MatchingRule)
+	}
+	return f, nil
+}
+
+// unescape decodes the hex pairs of a value that starts at offset. The value
+// must not hold a '*'; those of substrings filters have been split on.
+func (p *parser) unescape(raw string, offset int) (string, error) {
+	if !strings.ContainsAny(raw, `\*`) {
+		return raw, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case '*':
+			p.pos = offset + i
+			return "", p.errorf("unescaped '*' in value")
+		case '\\':
+			if i+2 >= len(raw) {
+				p.pos = offset + i
+				return "", p.errorf("incomplete escape in value")
+			}
+			hi, ok1 := unhex(raw[i+1])
+			lo, ok2 := unhex(raw[i+2])
+			if !ok1 || !ok2 {
+				p.pos = offset + i
+				return "", p.errorf("invalid escape %q in value", raw[i:i+3])
+			}
+			b.WriteByte(hi<<4 | lo)
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// validAttr returns true if s is an attribute description or OID: letters,
+// digits, hyphens, dots and the semicolons of attribute options.
+func validAttr(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '.', c == ';':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   Filter
+	}{
+		{"(cn=John Smith)", Eq("cn", "John Smith")},
+		{`(cn=\2a\29\5c\c3\a9)`, Eq("cn", `*)\é`)},
+		{"(cn=)", Eq("cn", "")},
+		{"(cn=a=b)", Eq("cn", "a=b")},
+		{"(cn;lang-en=x)", Eq("cn;lang-en", "x")},
+		{"(mail=*)", Present("mail")},
+		{"(cn=Jo*)", Substr("cn", "Jo", nil, "")},
+		{"(cn=*a**b*c)", Substr("cn", "", []string{"a", "b"}, "c")},
+		{`(cn=\2a*x)`, Substr("cn", "*", nil, "x")},
+		{"(uidNumber>=1000)", GE("uidNumber", "1000")},
+		{"(uidNumber<=1000)", LE("uidNumber", "1000")},
+		{"(sn~=smith)", Approx("sn", "smith")},
+		{"(cn:=Fred)", Extensible("cn", "", false, "Fred")},
+		{"(cn:dn:2.5.13.5:=Fred)", Extensible("cn", "2.5.13.5", true, "Fred")},
+		{"(:dn:caseIgnoreMatch:=Fred)", Extensible("", "caseIgnoreMatch", true, "Fred")},
+		{"(&)", &AndFilter{}},
+		{"(|)", &OrFilter{}},
+		{
+			" (&(objectClass=person) (|(uid=a)(!(mail=*)))) ",
+			And(Eq("objectClass", "person"), Or(Eq("uid", "a"), Not(Present("mail")))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := Parse(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f)
+
+			// The string form parses back to the same filter.
+			again, err := Parse(f.String())
+			require.NoError(t, err)
+			assert.Equal(t, f, again)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		filter string
+		offset int
+	}{
+		{"", 0},
+		{"cn=x", 0},
+		{"(cn=x", 5},
+		{"(cn=x))", 6},
+		{"(cn)", 1},
+		{"(=x)", 1},
+		{"(c n=x)", 1},
+		{"(cn=a(b)", 5},
+		{`(cn=\2)`, 4},
+		{`(cn=\zz)`, 4},
+		{"(cn>=a*)", 6},
+		{"(:=x)", 1},
+		{"(cn:dn:rule:extra:=x)", 1},
+		{"(&(cn=x)", 8},
+		{"(!)", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := Parse(tt.filter)
+			var syntaxErr *SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			assert.Equal(t, tt.offset, syntaxErr.Offset, err.Error())
+		})
+	}
+}
+
+func TestParse_Nesting(t *testing.T) {
+	deep := ""
+	for range maxDepth + 2 {
+		deep += "(!"
+	}
+	deep += "(cn=x)"
+	for range maxDepth + 2 {
+		deep += ")"
+	}
+	_, err := Parse(deep)
+	assert.Error(t, err)
+}
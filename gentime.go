@@ -0,0 +1,74 @@
+package ldapx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseGeneralizedTime parses a value in the GeneralizedTime syntax of RFC
+// 4517, such as "20240131120000Z" or "202401311200.5+0100". Minutes, seconds
+// and fractions are optional, and the fraction applies to the last unit given.
+// A value without a time zone is taken as UTC.
+func ParseGeneralizedTime(v string) (time.Time, error) {
+	s := v
+
+	// Split off the time zone.
+	loc := time.UTC
+	switch {
+	case strings.HasSuffix(s, "Z"):
+		s = s[:len(s)-1]
+	case len(s) > 5 && (s[len(s)-5] == '+' || s[len(s)-5] == '-'):
+		zone, err := time.Parse("-0700", s[len(s)-5:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid generalized time %q", v)
+		}
+		loc = zone.Location()
+		s = s[:len(s)-5]
+	}
+
+	// Split off the fraction, which is only digits.
+	var fraction string
+	if i := strings.IndexAny(s, ".,"); i >= 0 {
+		digits := s[i+1:]
+		if digits == "" || strings.Trim(digits, "0123456789") != "" {
+			return time.Time{}, fmt.Errorf("invalid generalized time %q", v)
+		}
+		fraction = "0." + digits
+		s = s[:i]
+	}
+
+	var unit time.Duration
+	var layout string
+	switch len(s) {
+	case 10:
+		layout, unit = "2006010215", time.Hour
+	case 12:
+		layout, unit = "200601021504", time.Minute
+	case 14:
+		layout, unit = "20060102150405", time.Second
+	default:
+		return time.Time{}, fmt.Errorf("invalid generalized time %q", v)
+	}
+
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid generalized time %q: %w", v, err)
+	}
+
+	if fraction != "" {
+		f, err := strconv.ParseFloat(fraction, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid generalized time %q: %w", v, err)
+		}
+		t = t.Add(time.Duration(f * float64(unit)))
+	}
+	return t, nil
+}
+
+// FormatGeneralizedTime formats a time in the GeneralizedTime syntax, in UTC
+// and without a fraction, such as "20240131120000Z".
+func FormatGeneralizedTime(t time.Time) string {
+	return t.UTC().Format("20060102150405Z")
+}
@@ -0,0 +1,139 @@
+package ldapx
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ApplyLDIFOption configures ApplyLDIF.
+type ApplyLDIFOption func(*applyLDIFOptions)
+
+// applyLDIFOptions holds the settings of ApplyLDIF.
+type applyLDIFOptions struct {
+	continueOnError bool               // Apply the remaining records after a record fails
+	readerOptions   []LDIFReaderOption // Options of the LDIF reader
+}
+
+// WithContinueOnError makes ApplyLDIF apply the remaining records after a
+// record fails, instead of stopping.
+func WithContinueOnError() ApplyLDIFOption {
+	return func(o *applyLDIFOptions) {
+		o.continueOnError = true
+	}
+}
+
+// WithLDIFReaderOptions configures the reader of the LDIF, for example with
+// WithLDIFURLResolver to allow values given by URL.
+func WithLDIFReaderOptions(opts ...LDIFReaderOption) ApplyLDIFOption {
+	return func(o *applyLDIFOptions) {
+		o.readerOptions = append(o.readerOptions, opts...)
+	}
+}
+
+// LDIFResult is the result of applying an LDIF record.
+type LDIFResult struct {
+	Line       int    // Line the record starts on
+	DN         string // DN of the record, empty if the record is not valid
+	ChangeType string // add, modify, delete or modrdn; empty if the record is not valid
+	Err        error  // Error applying the record, nil if it was applied
+}
+
+// LDIFReport holds the results of ApplyLDIF.
+type LDIFReport struct {
+	Results []LDIFResult // Results of the records, in order
+}
+
+// Applied returns the number of records that were applied.
+func (r *LDIFReport) Applied() int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the results of the records that failed.
+func (r *LDIFReport) Failed() []LDIFResult {
+	var failed []LDIFResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// ApplyLDIF applies the records read from r in order. Content records add
+// their entry. It stops at the first record that fails, unless
+// WithContinueOnError is given. The report holds the result of each record
+// read, and the error is that of the first record that failed.
+func (c *Conn) ApplyLDIF(r io.Reader, opts ...ApplyLDIFOption) (*LDIFReport, error) {
+	return c.ApplyLDIFContext(context.Background(), r, opts...)
+}
+
+// ApplyLDIFContext applies the records read from r in order. Content records
+// add their entry. It stops at the first record that fails, unless
+// WithContinueOnError is given, and when the context is done.
+func (c *Conn) ApplyLDIFContext(ctx context.Context, r io.Reader, opts ...ApplyLDIFOption) (*LDIFReport, error) {
+	o := &applyLDIFOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	report := &LDIFReport{}
+	var firstErr error
+	reader := NewLDIFReader(r, o.readerOptions...)
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		var result LDIFResult
+		record, err := reader.Next()
+		var syntaxErr *LDIFSyntaxError
+		switch {
+		case err == io.EOF:
+			return report, firstErr
+		case errors.As(err, &syntaxErr):
+			result = LDIFResult{Line: syntaxErr.Line, Err: err}
+		case err != nil:
+			return report, err
+		default:
+			result = LDIFResult{Line: record.Line, DN: record.Entry.DN}
+			result.ChangeType, result.Err = c.applyLDIFRecord(ctx, record)
+		}
+
+		report.Results = append(report.Results, result)
+		if result.Err != nil {
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			if !o.continueOnError {
+				return report, firstErr
+			}
+		}
+	}
+}
+
+// applyLDIFRecord applies a record. It returns the change type of the record.
+func (c *Conn) applyLDIFRecord(ctx context.Context, record *LDIFRecord) (string, error) {
+	e := record.Entry
+	switch {
+	case record.ModRDN != nil:
+		m := record.ModRDN
+		return "modrdn", c.ModifyDNContext(ctx, NewModifyDNRequest(e.DN, m.NewRDN, m.DeleteOldRDN, m.NewSuperior, record.Controls))
+	case e.ChangeType == ChangeAdd:
+		request := buildAddRequest(e.DN, e.Changes)
+		request.Controls = record.Controls
+		return "add", c.AddContext(ctx, request)
+	case e.ChangeType == ChangeDelete:
+		return "delete", c.DelContext(ctx, NewDelRequest(e.DN, record.Controls))
+	default:
+		request := buildModifyRequest(e.DN, e.Changes)
+		request.Controls = record.Controls
+		return "modify", c.ModifyContext(ctx, request)
+	}
+}
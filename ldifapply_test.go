@@ -0,0 +1,112 @@
+package ldapx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const applyTestLDIF = `version: 1
+
+dn: cn=new,dc=example,dc=com
+objectClass: person
+cn: new
+
+dn: cn=old,dc=example,dc=com
+changetype: modify
+replace: sn
+sn: Older
+-
+
+dn: cn=missing,dc=example,dc=com
+changetype: delete
+
+dn: cn=old,dc=example,dc=com
+changetype: modrdn
+newrdn: cn=older
+deleteoldrdn: 0
+
+dn: cn=broken,dc=example,dc=com
+changetype: frobnicate
+
+dn: cn=last,dc=example,dc=com
+changetype: add
+cn: last
+`
+
+func TestConn_ApplyLDIF(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=old,dc=example,dc=com", map[string][]string{"objectClass": {"person"}, "cn": {"old"}, "sn": {"Old"}})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	report, err := conn.ApplyLDIF(strings.NewReader(applyTestLDIF), WithContinueOnError())
+	require.Error(t, err)
+	assert.True(t, IsNotFound(err))
+
+	require.Len(t, report.Results, 6)
+	assert.Equal(t, 4, report.Applied())
+	failed := report.Failed()
+	require.Len(t, failed, 2)
+	assert.Equal(t, LDIFResult{Line: 13, DN: "cn=missing,dc=example,dc=com", ChangeType: "delete", Err: failed[0].Err}, failed[0])
+	assert.Equal(t, 21, failed[1].Line)
+	assert.Empty(t, failed[1].DN)
+
+	kinds := make([]string, 0, len(report.Results))
+	for _, r := range report.Results {
+		kinds = append(kinds, r.ChangeType)
+	}
+	assert.Equal(t, []string{"add", "modify", "delete", "modrdn", "", "add"}, kinds)
+
+	assert.Equal(t, []string{"new"}, s.get("cn=new,dc=example,dc=com")["cn"])
+	assert.Equal(t, []string{"Older"}, s.get("cn=older,dc=example,dc=com")["sn"])
+	assert.Nil(t, s.get("cn=old,dc=example,dc=com"))
+	assert.NotNil(t, s.get("cn=last,dc=example,dc=com"))
+}
+
+func TestConn_ApplyLDIFStopOnError(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=old,dc=example,dc=com", map[string][]string{"objectClass": {"person"}, "cn": {"old"}, "sn": {"Old"}})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	report, err := conn.ApplyLDIF(strings.NewReader(applyTestLDIF))
+	require.Error(t, err)
+	assert.True(t, IsNotFound(err))
+	require.Len(t, report.Results, 3)
+	assert.Equal(t, 2, report.Applied())
+	assert.Nil(t, s.get("cn=last,dc=example,dc=com"))
+	assert.NotNil(t, s.get("cn=old,dc=example,dc=com"))
+}
+
+func TestConn_ApplyLDIFURLValues(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	dir := t.TempDir()
+	value := filepath.Join(dir, "description.txt")
+	require.NoError(t, os.WriteFile(value, []byte("from file"), 0o600))
+	ldif := "dn: cn=new,dc=example,dc=com\ncn: new\ndescription:< file://" + value + "\n"
+
+	// Values given by URL are rejected unless a resolver is given.
+	_, err = conn.ApplyLDIF(strings.NewReader(ldif))
+	var syntaxErr *LDIFSyntaxError
+	assert.True(t, errors.As(err, &syntaxErr), "%v", err)
+	assert.Nil(t, s.get("cn=new,dc=example,dc=com"))
+
+	_, err = conn.ApplyLDIF(strings.NewReader(ldif), WithLDIFReaderOptions(WithLDIFURLResolver(LDIFFileResolver(dir))))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"from file"}, s.get("cn=new,dc=example,dc=com")["description"])
+}
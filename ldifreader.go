@@ -0,0 +1,414 @@
+package ldapx
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDIFRecord is a record read from LDIF. Content records and add records
+// hold a new entry with ChangeAdd. Modify records hold an entry with
+// ChangeUpdate whose Changes are the modifications, and delete records an
+// entry with ChangeDelete. Modrdn records hold an entry with ChangeUpdate and
+// no changes, and the rename in ModRDN.
+type LDIFRecord struct {
+	Entry    *Entry         // Entry of the record
+	ModRDN   *LDIFModRDN    // Rename of a modrdn record, nil for other records
+	Controls []ldap.Control // Controls of a change record
+	Line     int            // Line the record starts on
+}
+
+// LDIFModRDN is the rename of a modrdn record.
+type LDIFModRDN struct {
+	NewRDN       string // New RDN of the entry
+	DeleteOldRDN bool   // Remove the values of the old RDN from the entry
+	NewSuperior  string // DN of the new parent, empty to keep the parent
+}
+
+// LDIFSyntaxError is returned for a record that is not valid LDIF. The
+// reader skips the record, so reading may continue with the next one.
+type LDIFSyntaxError struct {
+	Line int    // Line the error was found on
+	Msg  string // Description of the error
+}
+
+// Error returns the error message.
+func (e *LDIFSyntaxError) Error() string {
+	return fmt.Sprintf("ldif: line %d: %s", e.Line, e.Msg)
+}
+
+// LDIFReader reads records from LDIF, as described in RFC 2849. Records are
+// read one at a time, so large files can be streamed. Values given by URL
+// are rejected unless a resolver is given with WithLDIFURLResolver.
+type LDIFReader struct {
+	r       *bufio.Reader
+	line    int             // Number of the last line read
+	read    bool            // A record has been read
+	resolve LDIFURLResolver // Reads values given by URL, nil to reject them
+}
+
+// LDIFURLResolver reads a value given by URL in LDIF.
+type LDIFURLResolver func(u *url.URL) ([]byte, error)
+
+// LDIFReaderOption configures an LDIFReader.
+type LDIFReaderOption func(*LDIFReader)
+
+// WithLDIFURLResolver reads values given by URL with resolve. Without it,
+// such values are rejected, since LDIF from an untrusted source could
+// otherwise read any local file.
+func WithLDIFURLResolver(resolve LDIFURLResolver) LDIFReaderOption {
+	return func(l *LDIFReader) {
+		l.resolve = resolve
+	}
+}
+
+// LDIFFileResolver returns a resolver that reads file URLs of files below
+// dir, and rejects any other URL.
+func LDIFFileResolver(dir string) LDIFURLResolver {
+	return func(u *url.URL) ([]byte, error) {
+		if u.Scheme != "file" {
+			return nil, errors.New("unsupported URL scheme " + u.Scheme)
+		}
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, errors.New("unsupported URL host " + u.Host)
+		}
+		base, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return nil, err
+		}
+		path, err := filepath.EvalSymlinks(filepath.FromSlash(u.Path))
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is not below %s", u.Path, dir)
+		}
+		return os.ReadFile(path)
+	}
+}
+
+// NewLDIFReader creates a reader of LDIF from r.
+func NewLDIFReader(r io.Reader, opts ...LDIFReaderOption) *LDIFReader {
+	l := &LDIFReader{r: bufio.NewReader(r)}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// ldifLine is a logical line, with its continuation lines unfolded.
+type ldifLine struct {
+	text string
+	num  int
+}
+
+// Next returns the next record. It returns io.EOF when there are no more
+// records, and an *LDIFSyntaxError for a record that is not valid.
+func (l *LDIFReader) Next() (*LDIFRecord, error) {
+	for {
+		lines, err := l.readRecord()
+		if err != nil {
+			return nil, err
+		}
+
+		// The version line may precede the first record, on its own or not.
+		if !l.read && len(lines) > 0 && strings.HasPrefix(strings.ToLower(lines[0].text), "version:") {
+			if v := strings.TrimSpace(lines[0].text[len("version:"):]); v != "1" {
+				return nil, &LDIFSyntaxError{Line: lines[0].num, Msg: fmt.Sprintf("unsupported version %q", v)}
+			}
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		l.read = true
+		return l.parseRecord(lines)
+	}
+}
+
+// readRecord reads the logical lines of the next record, skipping comments
+// and blank lines before it. A record with a syntax error is read to its end,
+// so that reading can continue with the next record.
+func (l *LDIFReader) readRecord() ([]ldifLine, error) {
+	var lines []ldifLine
+	var syntaxErr error
+	comment := false
+	for {
+		text, err := l.r.ReadString('\n')
+		if err != nil && (err != io.EOF || text == "") {
+			if err == io.EOF && (len(lines) > 0 || syntaxErr != nil) {
+				return lines, syntaxErr
+			}
+			return nil, err
+		}
+		l.line++
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+
+		switch {
+		case text == "":
+			if len(lines) > 0 || syntaxErr != nil {
+				return lines, syntaxErr
+			}
+			comment = false
+		case text[0] == ' ':
+			// A continuation line continues the previous line, which may
+			// have been a comment.
+			switch {
+			case comment:
+			case len(lines) == 0:
+				if syntaxErr == nil {
+					syntaxErr = &LDIFSyntaxError{Line: l.line, Msg: "continuation line without a line to continue"}
+				}
+			default:
+				lines[len(lines)-1].text += text[1:]
+			}
+		case text[0] == '#':
+			comment = true
+		default:
+			comment = false
+			lines = append(lines, ldifLine{text: text, num: l.line})
+		}
+	}
+}
+
+// parseRecord parses the logical lines of a record.
+func (l *LDIFReader) parseRecord(lines []ldifLine) (*LDIFRecord, error) {
+	r := &LDIFRecord{Line: lines[0].num}
+
+	name, dn, err := l.parseLine(lines[0])
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(name, "dn") {
+		return nil, &LDIFSyntaxError{Line: lines[0].num, Msg: fmt.Sprintf("record begins with %q instead of dn", name)}
+	}
+	lines = lines[1:]
+
+	// Controls precede the change type.
+	for len(lines) > 0 && strings.HasPrefix(strings.ToLower(lines[0].text), "control:") {
+		control, err := l.parseControl(lines[0])
+		if err != nil {
+			return nil, err
+		}
+		r.Controls = append(r.Controls, control)
+		lines = lines[1:]
+	}
+
+	changeType := ""
+	if len(lines) > 0 {
+		name, value, err := l.parseLine(lines[0])
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(name, "changetype") {
+			changeType = strings.ToLower(value)
+			lines = lines[1:]
+		}
+	}
+	if changeType == "" && len(r.Controls) > 0 {
+		return nil, &LDIFSyntaxError{Line: r.Line, Msg: "controls in a content record"}
+	}
+
+	switch changeType {
+	case "", "add":
+		// Values are collected first, since LDIF may hold values of an
+		// attribute that only differ in case.
+		var names []string
+		values := make(map[string][]string)
+		for _, line := range lines {
+			name, value, err := l.parseLine(line)
+			if err != nil {
+				return nil, err
+			}
+			key := strings.ToLower(name)
+			if _, ok := values[key]; !ok {
+				names = append(names, name)
+			}
+			values[key] = append(values[key], value)
+		}
+		r.Entry = NewEntry(dn)
+		for _, name := range names {
+			r.Entry.AddAttributeValues(name, values[strings.ToLower(name)])
+		}
+	case "delete":
+		if len(lines) > 0 {
+			return nil, &LDIFSyntaxError{Line: lines[0].num, Msg: "unexpected line in delete record"}
+		}
+		r.Entry = NewEntryFromLdapEntry(&ldap.Entry{DN: dn})
+		r.Entry.ChangeType = ChangeDelete
+	case "modify":
+		r.Entry = NewEntryFromLdapEntry(&ldap.Entry{DN: dn})
+		if err := l.parseModify(r.Entry, lines); err != nil {
+			return nil, err
+		}
+	case "modrdn", "moddn":
+		r.Entry = NewEntryFromLdapEntry(&ldap.Entry{DN: dn})
+		r.ModRDN, err = l.parseModRDN(r.Line, lines)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &LDIFSyntaxError{Line: r.Line, Msg: fmt.Sprintf("unknown changetype %q", changeType)}
+	}
+	return r, nil
+}
+
+// parseModify parses the modifications of a modify record into changes
+// of the entry.
+func (l *LDIFReader) parseModify(e *Entry, lines []ldifLine) error {
+	for len(lines) > 0 {
+		op, attr, err := l.parseLine(lines[0])
+		if err != nil {
+			return err
+		}
+		op = strings.ToLower(op)
+		switch op {
+		case "add", "delete", "replace", "increment":
+		default:
+			return &LDIFSyntaxError{Line: lines[0].num, Msg: fmt.Sprintf("unknown modify operation %q", op)}
+		}
+		start := lines[0].num
+		lines = lines[1:]
+
+		// The end of the record also ends the last modification, as many
+		// tools write it without the closing "-".
+		var values []string
+		for len(lines) > 0 {
+			if lines[0].text == "-" {
+				lines = lines[1:]
+				break
+			}
+			name, value, err := l.parseLine(lines[0])
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(name, attr) {
+				return &LDIFSyntaxError{Line: lines[0].num, Msg: fmt.Sprintf("value of %s in modification of %s", name, attr)}
+			}
+			values = append(values, value)
+			lines = lines[1:]
+		}
+		if op == "add" && len(values) == 0 || op == "increment" && len(values) != 1 {
+			return &LDIFSyntaxError{Line: start, Msg: fmt.Sprintf("wrong number of values for %s of %s", op, attr)}
+		}
+		e.AddAttributeChange(op, attr, values)
+	}
+	return nil
+}
+
+// parseModRDN parses the lines of a modrdn record.
+func (l *LDIFReader) parseModRDN(num int, lines []ldifLine) (*LDIFModRDN, error) {
+	m := &LDIFModRDN{}
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		name, value, err := l.parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.ToLower(name)
+		if seen[name] {
+			return nil, &LDIFSyntaxError{Line: line.num, Msg: "duplicate " + name}
+		}
+		seen[name] = true
+		switch name {
+		case "newrdn":
+			m.NewRDN = value
+		case "deleteoldrdn":
+			switch value {
+			case "0":
+			case "1":
+				m.DeleteOldRDN = true
+			default:
+				return nil, &LDIFSyntaxError{Line: line.num, Msg: fmt.Sprintf("invalid deleteoldrdn %q", value)}
+			}
+		case "newsuperior":
+			m.NewSuperior = value
+		default:
+			return nil, &LDIFSyntaxError{Line: line.num, Msg: fmt.Sprintf("unexpected %s in modrdn record", name)}
+		}
+	}
+	if !seen["newrdn"] || !seen["deleteoldrdn"] {
+		return nil, &LDIFSyntaxError{Line: num, Msg: "modrdn record without newrdn and deleteoldrdn"}
+	}
+	return m, nil
+}
+
+// parseControl parses a control line: the OID, an optional criticality
+// and an optional value.
+func (l *LDIFReader) parseControl(line ldifLine) (ldap.Control, error) {
+	_, spec, _ := strings.Cut(line.text, ":")
+	spec = strings.TrimLeft(spec, " ")
+
+	// The value is separated by a colon, which OIDs and criticality lack.
+	var value string
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		var err error
+		_, value, err = l.parseLine(ldifLine{text: "value" + spec[i:], num: line.num})
+		if err != nil {
+			return nil, err
+		}
+		spec = spec[:i]
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, &LDIFSyntaxError{Line: line.num, Msg: "invalid control"}
+	}
+	critical := false
+	if len(fields) == 2 {
+		switch fields[1] {
+		case "true":
+			critical = true
+		case "false":
+		default:
+			return nil, &LDIFSyntaxError{Line: line.num, Msg: fmt.Sprintf("invalid control criticality %q", fields[1])}
+		}
+	}
+	return NewControlString(fields[0], critical, value), nil
+}
+
+// parseLine splits a line into the attribute description and the value,
+// decoding base64 values and reading values given by URL.
+func (l *LDIFReader) parseLine(line ldifLine) (name, value string, err error) {
+	name, rest, ok := strings.Cut(line.text, ":")
+	if !ok || name == "" {
+		return "", "", &LDIFSyntaxError{Line: line.num, Msg: fmt.Sprintf("missing separator in %q", line.text)}
+	}
+
+	switch {
+	case strings.HasPrefix(rest, ":"):
+		b, err := base64.StdEncoding.DecodeString(strings.TrimLeft(rest[1:], " "))
+		if err != nil {
+			return "", "", &LDIFSyntaxError{Line: line.num, Msg: fmt.Sprintf("invalid base64 value of %s", name)}
+		}
+		return name, string(b), nil
+	case strings.HasPrefix(rest, "<"):
+		b, err := l.readURL(strings.TrimLeft(rest[1:], " "))
+		if err != nil {
+			return "", "", &LDIFSyntaxError{Line: line.num, Msg: err.Error()}
+		}
+		return name, string(b), nil
+	}
+	return name, strings.TrimLeft(rest, " "), nil
+}
+
+// readURL reads a value given by URL with the resolver.
+func (l *LDIFReader) readURL(s string) ([]byte, error) {
+	if l.resolve == nil {
+		return nil, fmt.Errorf("value given by URL %q, which is not allowed", s)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q", s)
+	}
+	return l.resolve(u)
+}
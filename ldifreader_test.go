@@ -0,0 +1,213 @@
+package ldapx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAllLDIF reads all records, failing the test on any error.
+func readAllLDIF(t *testing.T, s string, opts ...LDIFReaderOption) []*LDIFRecord {
+	t.Helper()
+
+	r := NewLDIFReader(strings.NewReader(s), opts...)
+	var records []*LDIFRecord
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestLDIFReader_Content(t *testing.T) {
+	dir := t.TempDir()
+	photo := filepath.Join(dir, "photo.jpg")
+	require.NoError(t, os.WriteFile(photo, []byte("\xff\xd8\xff"), 0o600))
+
+	records := readAllLDIF(t, `version: 1
+# A comment that is
+  folded
+dn: cn=Barbara Jensen,
+ dc=example,dc=com
+objectClass: person
+cn: Barbara Jensen
+cn: barbara jensen
+description:: IGxlYWRpbmcgc3BhY2U=
+jpegPhoto:< file://`+photo+`
+sn: Jensen
+
+dn:: Y249Y2Fmw6ksZGM9ZXhhbXBsZSxkYz1jb20=
+cn: caf
+ é
+`, WithLDIFURLResolver(LDIFFileResolver(dir)))
+
+	require.Len(t, records, 2)
+	e := records[0].Entry
+	assert.Equal(t, 4, records[0].Line)
+	assert.Equal(t, "cn=Barbara Jensen,dc=example,dc=com", e.DN)
+	assert.Equal(t, ChangeAdd, e.ChangeType)
+	assert.Equal(t, []string{"Barbara Jensen", "barbara jensen"}, e.GetAttributeValues("cn"))
+	assert.Equal(t, " leading space", e.GetAttributeValue("description"))
+	assert.Equal(t, "\xff\xd8\xff", e.GetAttributeValue("jpegPhoto"))
+	assert.Equal(t, "Jensen", e.GetAttributeValue("sn"))
+	assert.Equal(t, AttributeChange{Action: "add", Attr: "objectClass", Value: []string{"person"}}, e.Changes[0])
+	assert.Nil(t, records[0].ModRDN)
+
+	assert.Equal(t, "cn=café,dc=example,dc=com", records[1].Entry.DN)
+	assert.Equal(t, "café", records[1].Entry.GetAttributeValue("cn"))
+}
+
+func TestLDIFReader_Changes(t *testing.T) {
+	records := readAllLDIF(t, `version: 1
+
+dn: cn=new,dc=example,dc=com
+control: 1.2.840.113556.1.4.417 true
+changetype: add
+cn: new
+
+dn: cn=old,dc=example,dc=com
+changetype: modify
+add: mail
+mail: new@example.com
+-
+replace: cn
+cn: Old
+-
+delete: description
+-
+increment: uidNumber
+uidNumber: 1
+-
+delete: member
+member:: Y249YSxkYz1leGFtcGxl
+
+dn: cn=gone,dc=example,dc=com
+control: 1.2.840.113556.1.4.805 false:: AQ==
+changetype: delete
+
+dn: cn=old,dc=example,dc=com
+changetype: modrdn
+newrdn: cn=older
+deleteoldrdn: 1
+newsuperior: ou=archive,dc=example,dc=com
+`)
+	require.Len(t, records, 4)
+
+	add := records[0]
+	assert.Equal(t, ChangeAdd, add.Entry.ChangeType)
+	require.Len(t, add.Controls, 1)
+	assert.Equal(t, "1.2.840.113556.1.4.417", add.Controls[0].GetControlType())
+	assert.True(t, add.Controls[0].(*ldap.ControlString).Criticality)
+
+	modify := records[1].Entry
+	assert.Equal(t, ChangeUpdate, modify.ChangeType)
+	assert.Equal(t, []AttributeChange{
+		{Action: "add", Attr: "mail", Value: []string{"new@example.com"}},
+		{Action: "replace", Attr: "cn", Value: []string{"Old"}},
+		{Action: "delete", Attr: "description"},
+		{Action: "increment", Attr: "uidNumber", Value: []string{"1"}},
+		{Action: "delete", Attr: "member", Value: []string{"cn=a,dc=example"}},
+	}, modify.Changes)
+
+	del := records[2]
+	assert.Equal(t, ChangeDelete, del.Entry.ChangeType)
+	require.Len(t, del.Controls, 1)
+	control := del.Controls[0].(*ldap.ControlString)
+	assert.False(t, control.Criticality)
+	assert.Equal(t, "\x01", control.ControlValue)
+
+	rename := records[3]
+	assert.Equal(t, &LDIFModRDN{NewRDN: "cn=older", DeleteOldRDN: true, NewSuperior: "ou=archive,dc=example,dc=com"}, rename.ModRDN)
+	assert.Empty(t, rename.Entry.Changes)
+}
+
+func TestLDIFReader_RoundTrip(t *testing.T) {
+	e := NewEntry("cn=Test,dc=example,dc=com")
+	e.AddAttributeValues("objectClass", []string{"top", "person"})
+	e.AddAttributeValues("description", []string{strings.Repeat("long value ", 20), "naïve", " space"})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteLDIF(&buf, e))
+
+	records := readAllLDIF(t, buf.String())
+	require.Len(t, records, 1)
+	assert.Equal(t, e.ToLDIF(), records[0].Entry.ToLDIF())
+}
+
+func TestLDIFReader_SyntaxErrors(t *testing.T) {
+	r := NewLDIFReader(strings.NewReader(`dn: cn=a,dc=example,dc=com
+changetype: modify
+frobnicate: cn
+-
+
+cn: no dn
+
+dn: cn=b,dc=example,dc=com
+changetype: modrdn
+newrdn: cn=c
+
+dn: cn=d,dc=example,dc=com
+description:: not base64!
+
+dn: cn=e,dc=example,dc=com
+cn: e
+`))
+
+	for _, line := range []int{3, 6, 8, 13} {
+		_, err := r.Next()
+		var syntaxErr *LDIFSyntaxError
+		require.True(t, errors.As(err, &syntaxErr), "%v", err)
+		assert.Equal(t, line, syntaxErr.Line)
+	}
+
+	// Reading continues after records that are not valid.
+	record, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "cn=e,dc=example,dc=com", record.Entry.DN)
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLDIFReader_Version(t *testing.T) {
+	_, err := NewLDIFReader(strings.NewReader("version: 2\n\ndn: cn=a\n")).Next()
+	assert.Error(t, err)
+
+	_, err = NewLDIFReader(strings.NewReader("dn: cn=a\ncn:< http://example.com/a\n")).Next()
+	assert.Error(t, err)
+}
+
+func TestLDIFReader_URLValues(t *testing.T) {
+	dir := t.TempDir()
+	inside := filepath.Join(dir, "value.txt")
+	require.NoError(t, os.WriteFile(inside, []byte("from file"), 0o600))
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o600))
+
+	// Values given by URL are rejected by default.
+	_, err := NewLDIFReader(strings.NewReader("dn: cn=a\ndescription:< file://" + inside + "\n")).Next()
+	var syntaxErr *LDIFSyntaxError
+	require.True(t, errors.As(err, &syntaxErr), "%v", err)
+	assert.Equal(t, 2, syntaxErr.Line)
+
+	// The file resolver only reads files below its directory.
+	resolver := WithLDIFURLResolver(LDIFFileResolver(dir))
+	record, err := NewLDIFReader(strings.NewReader("dn: cn=a\ndescription:< file://"+inside+"\n"), resolver).Next()
+	require.NoError(t, err)
+	assert.Equal(t, "from file", record.Entry.GetAttributeValue("description"))
+
+	for _, u := range []string{"file://" + outside, "file://" + dir + "/../" + filepath.Base(outside), "http://example.com/a"} {
+		_, err = NewLDIFReader(strings.NewReader("dn: cn=a\ndescription:< "+u+"\n"), resolver).Next()
+		assert.True(t, errors.As(err, &syntaxErr), "%s: %v", u, err)
+	}
+}
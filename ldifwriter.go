@@ -0,0 +1,204 @@
+package ldapx
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ldifLineLength is the column at which LDIF lines are folded.
+const ldifLineLength = 76
+
+// LDIFWriter writes entries and changes as LDIF, as described in RFC 2849.
+// Values that are not safe strings are base64 encoded and long lines are
+// folded. The "version: 1" header is written before the first record.
+type LDIFWriter struct {
+	w       *bufio.Writer
+	records int // Number of records written
+}
+
+// NewLDIFWriter creates a writer of LDIF to w. Flush must be called once
+// all records are written.
+func NewLDIFWriter(w io.Writer) *LDIFWriter {
+	return &LDIFWriter{w: bufio.NewWriter(w)}
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (l *LDIFWriter) Flush() error {
+	return l.w.Flush()
+}
+
+// WriteEntry writes the attributes of the entry as a content record.
+func (l *LDIFWriter) WriteEntry(e *Entry) error {
+	var b strings.Builder
+	writeEntryLDIF(&b, e)
+	return l.writeRecord(b.String())
+}
+
+// WriteChange writes the pending change of the entry as a change record:
+// an add record with its attributes for a new entry, a modify record with
+// its changes for an updated entry, or a delete record. Nothing is written
+// for an updated entry without changes.
+func (l *LDIFWriter) WriteChange(e *Entry) error {
+	var b strings.Builder
+	if !writeChangeLDIF(&b, e) {
+		return nil
+	}
+	return l.writeRecord(b.String())
+}
+
+// WriteModRDN writes a modrdn change record, which renames the entry with
+// the given DN to the new RDN and, if newSuperior is not empty, moves it
+// under newSuperior.
+func (l *LDIFWriter) WriteModRDN(dn, newRDN string, deleteOldRDN bool, newSuperior string) error {
+	var b strings.Builder
+	writeLDIFLine(&b, "dn", dn)
+	writeLDIFLine(&b, "changetype", "modrdn")
+	writeLDIFLine(&b, "newrdn", newRDN)
+	if deleteOldRDN {
+		writeLDIFLine(&b, "deleteoldrdn", "1")
+	} else {
+		writeLDIFLine(&b, "deleteoldrdn", "0")
+	}
+	if newSuperior != "" {
+		writeLDIFLine(&b, "newsuperior", newSuperior)
+	}
+	return l.writeRecord(b.String())
+}
+
+// writeRecord writes a record, preceded by the version header or the blank
+// line separating it from the previous record.
+func (l *LDIFWriter) writeRecord(record string) error {
+	sep := "\n"
+	if l.records == 0 {
+		sep = "version: 1\n\n"
+	}
+	l.records++
+	if _, err := l.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err := l.w.WriteString(record)
+	return err
+}
+
+// writeEntryLDIF writes the content record of the entry.
+func writeEntryLDIF(b *strings.Builder, e *Entry) {
+	writeLDIFLine(b, "dn", e.DN)
+	writeAttributesLDIF(b, e)
+}
+
+// writeAttributesLDIF writes a line for each attribute value of the entry.
+func writeAttributesLDIF(b *strings.Builder, e *Entry) {
+	for _, name := range ldifAttributeNames(e) {
+		a := e.Attributes.Get(name)
+		for _, v := range a.Values {
+			writeLDIFLine(b, a.Name, v)
+		}
+	}
+}
+
+// writeChangeLDIF writes the change record of the entry. It returns false if
+// the entry has no change to write.
+func writeChangeLDIF(b *strings.Builder, e *Entry) bool {
+	switch e.ChangeType {
+	case ChangeAdd:
+		writeLDIFLine(b, "dn", e.DN)
+		writeLDIFLine(b, "changetype", "add")
+		writeAttributesLDIF(b, e)
+	case ChangeDelete:
+		writeLDIFLine(b, "dn", e.DN)
+		writeLDIFLine(b, "changetype", "delete")
+	default:
+		if len(e.Changes) == 0 {
+			return false
+		}
+		writeLDIFLine(b, "dn", e.DN)
+		writeLDIFLine(b, "changetype", "modify")
+		for _, change := range e.Changes {
+			writeLDIFLine(b, change.Action, change.Attr)
+			for _, v := range change.Value {
+				writeLDIFLine(b, change.Attr, v)
+			}
+			b.WriteString("-\n")
+		}
+	}
+	return true
+}
+
+// ldifAttributeNames returns the attribute names of the entry in the order
+// they are written: objectClass first, then the others sorted.
+func ldifAttributeNames(e *Entry) []string {
+	names := e.AttributeNames()
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := strings.EqualFold(names[i], "objectClass"), strings.EqualFold(names[j], "objectClass")
+		if oi != oj {
+			return oi
+		}
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+// writeLDIFLine writes an "attr: value" line, base64 encoding the value if it
+// is not a safe string and folding the line if it is too long.
+func writeLDIFLine(b *strings.Builder, attr, value string) {
+	line := attr + ": " + value
+	if !isSafeLDIFString(value) {
+		line = attr + ":: " + base64.StdEncoding.EncodeToString([]byte(value))
+	}
+
+	// Continuation lines begin with a space, so they hold one character less.
+	width := ldifLineLength
+	for len(line) > width {
+		b.WriteString(line[:width])
+		b.WriteString("\n ")
+		line = line[width:]
+		width = ldifLineLength - 1
+	}
+	b.WriteString(line)
+	b.WriteByte('\n')
+}
+
+// isSafeLDIFString returns true if the value can be written as is: it holds
+// only ASCII characters other than NUL, LF and CR, does not begin with a
+// space, colon or '<', and does not end with a space.
+func isSafeLDIFString(v string) bool {
+	if v == "" {
+		return true
+	}
+	switch v[0] {
+	case ' ', ':', '<':
+		return false
+	}
+	if v[len(v)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c == 0 || c == '\n' || c == '\r' || c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangeLDIF converts the pending change of the entry to an LDIF change
+// record. It returns an empty string if the entry has no change.
+func (e *Entry) ChangeLDIF() string {
+	var b strings.Builder
+	writeChangeLDIF(&b, e)
+	return b.String()
+}
+
+// WriteLDIF writes the entries to w as LDIF content records.
+func WriteLDIF(w io.Writer, entries ...*Entry) error {
+	l := NewLDIFWriter(w)
+	for _, e := range entries {
+		if err := l.WriteEntry(e); err != nil {
+			return err
+		}
+	}
+	return l.Flush()
+}
@@ -0,0 +1,126 @@
+package ldapx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntry_ToLDIF(t *testing.T) {
+	e := NewEntry("cn=Test,dc=example,dc=com")
+	e.AddAttributeValues("sn", []string{"Test"})
+	e.AddAttributeValues("objectClass", []string{"top", "person"})
+	e.AddAttributeValues("description", []string{" leading space", ":colon", "<angle", "trailing ", "naïve", "line\nbreak", "plain"})
+	e.AddAttributeValues("cn", []string{"Test"})
+
+	assert.Equal(t, `dn: cn=Test,dc=example,dc=com
+objectClass: top
+objectClass: person
+cn: Test
+description:: IGxlYWRpbmcgc3BhY2U=
+description:: OmNvbG9u
+description:: PGFuZ2xl
+description:: dHJhaWxpbmcg
+description:: bmHDr3Zl
+description:: bGluZQpicmVhaw==
+description: plain
+sn: Test
+`, e.ToLDIF())
+}
+
+func TestWriteLDIFLine_Folding(t *testing.T) {
+	value := strings.Repeat("abcdefghij", 20)
+
+	var b strings.Builder
+	writeLDIFLine(&b, "description", value)
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+
+	require.Len(t, lines, 3)
+	assert.Len(t, lines[0], 76)
+	assert.Len(t, lines[1], 76)
+	var unfolded strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			require.True(t, strings.HasPrefix(line, " "))
+			line = line[1:]
+		}
+		unfolded.WriteString(line)
+	}
+	assert.Equal(t, "description: "+value, unfolded.String())
+
+	b.Reset()
+	writeLDIFLine(&b, "cn", strings.Repeat("x", 72))
+	assert.Equal(t, "cn: "+strings.Repeat("x", 72)+"\n", b.String())
+}
+
+func TestLDIFWriter_Changes(t *testing.T) {
+	added := NewEntry("cn=new,dc=example,dc=com")
+	added.AddAttributeValues("objectClass", []string{"person"})
+	added.AddAttributeValues("cn", []string{"new"})
+
+	modified := NewEntryFromLdapEntry(ldap.NewEntry("cn=old,dc=example,dc=com", map[string][]string{
+		"mail":        {"old@example.com"},
+		"description": {"gone"},
+		"cn":          {"old"},
+	}))
+	modified.AddAttributeValue("mail", "new@example.com")
+	modified.ReplaceAttributeValue("cn", "Old")
+	modified.DeleteAttribute("description")
+
+	unchanged := NewEntryFromLdapEntry(ldap.NewEntry("cn=same,dc=example,dc=com", nil))
+
+	deleted := NewEntryFromLdapEntry(ldap.NewEntry("cn=gone,dc=example,dc=com", nil))
+	deleted.ChangeType = ChangeDelete
+
+	var buf bytes.Buffer
+	w := NewLDIFWriter(&buf)
+	require.NoError(t, w.WriteChange(added))
+	require.NoError(t, w.WriteChange(modified))
+	require.NoError(t, w.WriteChange(unchanged))
+	require.NoError(t, w.WriteChange(deleted))
+	require.NoError(t, w.WriteModRDN("cn=old,dc=example,dc=com", "cn=older", true, "ou=archive,dc=example,dc=com"))
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, `version: 1
+
+dn: cn=new,dc=example,dc=com
+changetype: add
+objectClass: person
+cn: new
+
+dn: cn=old,dc=example,dc=com
+changetype: modify
+add: mail
+mail: new@example.com
+-
+replace: cn
+cn: Old
+-
+delete: description
+-
+
+dn: cn=gone,dc=example,dc=com
+changetype: delete
+
+dn: cn=old,dc=example,dc=com
+changetype: modrdn
+newrdn: cn=older
+deleteoldrdn: 1
+newsuperior: ou=archive,dc=example,dc=com
+`, buf.String())
+
+	assert.Empty(t, unchanged.ChangeLDIF())
+}
+
+func TestWriteLDIF(t *testing.T) {
+	e := NewEntry("cn=café,dc=example,dc=com")
+	e.AddAttributeValue("cn", "café")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteLDIF(&buf, e))
+	assert.Equal(t, "version: 1\n\ndn:: Y249Y2Fmw6ksZGM9ZXhhbXBsZSxkYz1jb20=\ncn:: Y2Fmw6k=\n", buf.String())
+}
@@ -1,62 +1,135 @@
 package ldapx
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
 )
 
-// Lookup searches for the given DN and returns the entry.
-func (c *Conn) Lookup(dn string) (*Entry, error) {
-	result, err := c.Search(NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.DerefAlways, 1, 0, false, "(objectclass=*)", nil, nil))
+// LookupOption configures a Lookup.
+type LookupOption func(*lookupOptions)
+
+// lookupOptions holds the settings of a Lookup.
+type lookupOptions struct {
+	attributes []string // Attributes to return; all user attributes if empty
+}
+
+// WithAttributes sets the attributes returned by Lookup. Use "*" for all user
+// attributes and "+" for all operational attributes.
+func WithAttributes(attributes ...string) LookupOption {
+	return func(o *lookupOptions) {
+		o.attributes = append(o.attributes, attributes...)
+	}
+}
+
+// Lookup searches for the given DN and returns the entry. An ErrNotFound
+// error is returned if the entry does not exist or is not visible.
+func (c *Conn) Lookup(dn string, opts ...LookupOption) (*Entry, error) {
+	return c.LookupContext(context.Background(), dn, opts...)
+}
+
+// LookupContext searches for the given DN and returns the entry. An
+// ErrNotFound error is returned if the entry does not exist or is not visible.
+func (c *Conn) LookupContext(ctx context.Context, dn string, opts ...LookupOption) (*Entry, error) {
+	o := &lookupOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	result, err := c.SearchContext(ctx, NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.DerefAlways, 1, 0, false, "(objectclass=*)", o.attributes, nil))
 	if err != nil {
 		return nil, err
 	}
 
-	return NewEntryFromLdapEntry(result.Entries[0]), nil
+	// Access controls or proxies can hide an entry without returning an error.
+	if len(result.Entries) == 0 {
+		return nil, &OpError{Op: "search", DN: dn, Err: ErrNotFound}
+	}
+
+	return c.bindEntry(NewEntryFromLdapEntry(result.Entries[0])), nil
+}
+
+// Exists returns true if the entry with the given DN exists and is visible.
+func (c *Conn) Exists(dn string) (bool, error) {
+	return c.ExistsContext(context.Background(), dn)
+}
+
+// ExistsContext returns true if the entry with the given DN exists and is visible.
+func (c *Conn) ExistsContext(ctx context.Context, dn string) (bool, error) {
+	// "1.1" requests no attributes.
+	_, err := c.LookupContext(ctx, dn, WithAttributes("1.1"))
+	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
 // LookupOrNew searches for the given DN and returns the entry if found, otherwise a new entry is created with the given DN.
 func (c *Conn) LookupOrNew(dn string) (*Entry, error) {
-	entry, err := c.Lookup(dn)
+	return c.LookupOrNewContext(context.Background(), dn)
+}
+
+// LookupOrNewContext searches for the given DN and returns the entry if found, otherwise a new entry is created with the given DN.
+func (c *Conn) LookupOrNewContext(ctx context.Context, dn string) (*Entry, error) {
+	entry, err := c.LookupContext(ctx, dn)
 	if err != nil {
-		if !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		if !IsNotFound(err) {
 			return nil, err
 		}
-		entry = NewEntry(dn)
+		entry = c.bindEntry(NewEntry(dn))
 	}
 	return entry, nil
 }
 
-// FindEntry searches using given DN base and returns the first entry that matches the filter.
+// FindEntry searches using given DN base and returns the first entry that
+// matches the filter. An ErrNotFound error is returned if no entry matches.
 func (c *Conn) FindEntry(dn string, filter string, attributes []string) (*Entry, error) {
 	return FindEntry(c, dn, filter, attributes)
 }
 
+// FindEntryContext searches using given DN base and returns the first entry that matches the filter.
+func (c *Conn) FindEntryContext(ctx context.Context, dn string, filter string, attributes []string) (*Entry, error) {
+	return FindEntryContext(ctx, c, dn, filter, attributes)
+}
+
 // FindEntry searches using given DN base and returns the first entry that matches the filter using the given connection.
 func FindEntry(conn *Conn, dn string, filter string, attributes []string) (*Entry, error) {
-	result, err := conn.QuickSearch(dn, filter, attributes)
+	return FindEntryContext(context.Background(), conn, dn, filter, attributes)
+}
+
+// FindEntryContext searches using given DN base and returns the first entry that matches the filter using the given connection.
+func FindEntryContext(ctx context.Context, conn *Conn, dn string, filter string, attributes []string) (*Entry, error) {
+	result, err := conn.QuickSearchContext(ctx, dn, filter, attributes)
 	if err != nil {
 		return nil, err
 	}
 
 	// No entries found
 	if len(result.Entries) == 0 {
-		return nil, nil
+		return nil, &OpError{Op: "search", DN: dn, Err: ErrNotFound}
 	}
 
 	// More than one entry matched
 	if len(result.Entries) > 1 {
-		return nil, fmt.Errorf("multiple entries matched")
+		return nil, &OpError{Op: "search", DN: dn, Err: ErrMultipleEntries}
 	}
 
-	return NewEntryFromLdapEntry(result.Entries[0]), nil
+	return conn.bindEntry(NewEntryFromLdapEntry(result.Entries[0])), nil
 }
 
 // QuickSearch performs a search using the given DN base, filter and attributes.
 func (c *Conn) QuickSearch(dn string, filter string, attributes []string) (*ldap.SearchResult, error) {
-	return c.Search(NewSearchRequest(dn, ldap.ScopeWholeSubtree, ldap.DerefAlways, 0, 0, false, filter, attributes, nil))
+	return c.QuickSearchContext(context.Background(), dn, filter, attributes)
+}
+
+// QuickSearchContext performs a search using the given DN base, filter and attributes.
+func (c *Conn) QuickSearchContext(ctx context.Context, dn string, filter string, attributes []string) (*ldap.SearchResult, error) {
+	return c.SearchContext(ctx, NewSearchRequest(dn, ldap.ScopeWholeSubtree, ldap.DerefAlways, 0, 0, false, filter, attributes, nil))
 }
 
 // GetAttributeFromDN return value of first (leftmost) RDN that matches attribute name
@@ -74,5 +147,5 @@ func GetAttributeFromDN(attr, dn string) (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf("dn does not contain attribute '%s'", attr)
+	return "", fmt.Errorf("dn does not contain attribute '%s': %w", attr, ErrNoSuchAttribute)
 }
@@ -1,6 +1,11 @@
 package ldapx
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 func TestGetAttributeFromDN(t *testing.T) {
 	type args struct {
@@ -33,3 +38,63 @@ func TestGetAttributeFromDN(t *testing.T) {
 		})
 	}
 }
+
+func TestConn_Lookup(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=test,dc=example,dc=com", map[string][]string{"objectClass": {"person"}, "cn": {"test"}, "sn": {"Test"}})
+	// An entry without objectClass never matches the lookup filter, like an
+	// entry hidden by access controls.
+	s.put("cn=hidden,dc=example,dc=com", map[string][]string{"cn": {"hidden"}})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	entry, err := conn.Lookup("cn=test,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.Equal(t, "Test", entry.GetAttributeValue("sn"))
+
+	entry, err = conn.Lookup("cn=test,dc=example,dc=com", WithAttributes("cn"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cn"}, entry.AttributeNames())
+
+	_, err = conn.Lookup("cn=missing,dc=example,dc=com")
+	assert.True(t, IsNotFound(err))
+
+	_, err = conn.Lookup("cn=hidden,dc=example,dc=com")
+	assert.True(t, IsNotFound(err))
+
+	entry, err = conn.LookupOrNew("cn=hidden,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeAdd, entry.ChangeType)
+}
+
+func TestConn_Exists(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=test,dc=example,dc=com", map[string][]string{"objectClass": {"person"}, "cn": {"test"}})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	exists, err := conn.Exists("cn=test,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = conn.Exists("cn=missing,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestConn_FindEntryNotFound(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("dc=example,dc=com", map[string][]string{"objectClass": {"domain"}})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	entry, err := conn.FindEntry("dc=example,dc=com", "(cn=missing)", nil)
+	assert.Nil(t, entry)
+	assert.True(t, IsNotFound(err))
+}
@@ -0,0 +1,400 @@
+package ldapx
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Struct fields are mapped to attributes with tags of the form
+//
+//	Mail     string    `ldap:"mail"`
+//	MemberOf []string  `ldap:"memberOf,omitempty"`
+//	DN       string    `ldap:"dn"`
+//	Created  time.Time `ldap:"createTimestamp"`
+//
+// Fields without an ldap tag, or tagged "-", are ignored. The fields of
+// embedded structs, and of embedded pointers to structs, are mapped as if
+// they were fields of the outer struct. Unmarshal allocates an embedded
+// pointer when it sets one of its fields; Marshal skips the fields of a nil
+// one, leaving their attributes as they are. Embedded pointers to unexported
+// struct types cannot be allocated and are reported as an error.
+//
+// Supported field types are string, bool, the integer types, time.Time (as
+// GeneralizedTime), []byte, types implementing encoding.TextMarshaler and
+// encoding.TextUnmarshaler, pointers to these and slices of these for
+// multi-valued attributes. The "dn" tag maps a string field to the DN.
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	byteSliceType       = reflect.TypeOf([]byte(nil))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// structField describes a struct field mapped to an attribute.
+type structField struct {
+	index     []int  // Index of the field, for reflect.Value.FieldByIndex
+	name      string // Go name of the field, for error messages
+	attr      string // Attribute name, or "dn"
+	omitEmpty bool   // Do not write the attribute when the field is empty
+	multi     bool   // The field holds all values of the attribute
+}
+
+// structFieldCache caches the fields of struct types.
+var structFieldCache sync.Map // map[reflect.Type][]structField
+
+// structFields returns the mapped fields of a struct type.
+func structFields(t reflect.Type) ([]structField, error) {
+	if f, ok := structFieldCache.Load(t); ok {
+		return f.([]structField), nil
+	}
+
+	var fields []structField
+	walking := make(map[reflect.Type]bool)
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		if walking[t] {
+			return fmt.Errorf("ldapx: struct %s embeds itself", t)
+		}
+		walking[t] = true
+		defer delete(walking, t)
+
+		for i := range t.NumField() {
+			f := t.Field(i)
+			tag, tagged := f.Tag.Lookup("ldap")
+			idx := append(append([]int(nil), index...), i)
+
+			if !tagged && f.Anonymous {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct {
+					if !f.IsExported() {
+						return fmt.Errorf("ldapx: embedded field %s is a pointer to an unexported struct type", f.Name)
+					}
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					if err := walk(ft, idx); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if !tagged || tag == "-" || !f.IsExported() {
+				continue
+			}
+
+			name, opts, _ := strings.Cut(tag, ",")
+			sf := structField{index: idx, name: f.Name, attr: name, omitEmpty: opts == "omitempty"}
+			if name == "" {
+				return fmt.Errorf("ldapx: field %s has an empty attribute name", f.Name)
+			}
+			if opts != "" && opts != "omitempty" {
+				return fmt.Errorf("ldapx: field %s has unknown tag option %q", f.Name, opts)
+			}
+
+			if strings.EqualFold(name, "dn") {
+				if f.Type.Kind() != reflect.String {
+					return fmt.Errorf("ldapx: dn field %s must be a string", f.Name)
+				}
+				sf.attr = "dn"
+			} else {
+				ft := f.Type
+				if ft.Kind() == reflect.Slice && ft != byteSliceType && !ft.Implements(textMarshalerType) {
+					sf.multi = true
+					ft = ft.Elem()
+				}
+				if !supportedType(ft) {
+					return fmt.Errorf("ldapx: field %s has unsupported type %s", f.Name, f.Type)
+				}
+			}
+			fields = append(fields, sf)
+		}
+		return nil
+	}
+	if err := walk(t, nil); err != nil {
+		return nil, err
+	}
+
+	structFieldCache.Store(t, fields)
+	return fields, nil
+}
+
+// supportedType returns true if values of the type can be converted to and
+// from attribute values.
+func supportedType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || t == byteSliceType || t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// structValue returns the struct v points to.
+func structValue(v any, op string) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("ldapx: %s requires a non-nil pointer to a struct, got %T", op, v)
+	}
+	return rv.Elem(), nil
+}
+
+// Unmarshal copies the attributes of the entry into the struct v points to,
+// as described by its ldap tags. Fields of attributes the entry does not
+// have are left unchanged.
+func (e *Entry) Unmarshal(v any) error {
+	rv, err := structValue(v, "Unmarshal")
+	if err != nil {
+		return err
+	}
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		if f.attr == "dn" {
+			fv, _ := fieldByIndex(rv, f.index, true)
+			fv.SetString(e.DN)
+			continue
+		}
+
+		a := e.Attributes.Get(f.attr)
+		if a == nil || len(a.Values) == 0 {
+			continue
+		}
+		values := a.Values
+		fv, _ := fieldByIndex(rv, f.index, true)
+
+		if !f.multi {
+			err = decodeValue(fv, []byte(values[0]))
+		} else {
+			s := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for i, value := range values {
+				if err = decodeValue(s.Index(i), []byte(value)); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				fv.Set(s)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("ldapx: attribute %s into field %s: %w", f.attr, f.name, err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndex returns the field of v with the given index. Nil embedded
+// pointers on the way are allocated if alloc is true; otherwise ok is false.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (field reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// decodeValue sets v from an attribute value.
+func decodeValue(v reflect.Value, value []byte) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := decodeValue(p.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		t, err := ParseGeneralizedTime(string(value))
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == byteSliceType:
+		v.SetBytes(append([]byte(nil), value...))
+		return nil
+	case v.Addr().Type().Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(value)
+	}
+
+	s := string(value)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// parseBool parses a value of the LDAP Boolean syntax, "TRUE" or "FALSE".
+// Case is ignored for the benefit of servers that are lax about it.
+func parseBool(s string) (bool, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", s)
+}
+
+// MarshalEntry creates a new entry from the struct v points to, as described
+// by its ldap tags. If dn is empty the DN is taken from the field tagged "dn".
+func MarshalEntry(dn string, v any) (*Entry, error) {
+	e := NewEntry(dn)
+	if err := e.Marshal(v); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Marshal copies the struct v points to into the entry, as described by its
+// ldap tags. Attributes are updated with the change-tracking mutators, so
+// only values that differ from those of the entry become changes. Empty
+// fields delete their attribute unless they are tagged omitempty.
+func (e *Entry) Marshal(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("ldapx: Marshal requires a struct or a pointer to a struct, got %T", v)
+	}
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		fv, ok := fieldByIndex(rv, f.index, false)
+		if !ok {
+			continue
+		}
+		if f.attr == "dn" {
+			if e.DN == "" {
+				e.DN = fv.String()
+			}
+			continue
+		}
+
+		var values []string
+		if !f.multi {
+			if value, ok, err := encodeValue(fv); err != nil {
+				return fmt.Errorf("ldapx: field %s into attribute %s: %w", f.name, f.attr, err)
+			} else if ok {
+				values = []string{value}
+			}
+		} else {
+			for i := range fv.Len() {
+				value, ok, err := encodeValue(fv.Index(i))
+				if err != nil {
+					return fmt.Errorf("ldapx: field %s into attribute %s: %w", f.name, f.attr, err)
+				}
+				if ok {
+					values = append(values, value)
+				}
+			}
+		}
+
+		switch {
+		case len(values) == 0 && f.omitEmpty:
+		case len(values) == 0:
+			if e.AttributeExists(f.attr) {
+				e.DeleteAttribute(f.attr)
+			}
+		case f.multi:
+			e.SyncAttributeValues(f.attr, values)
+		default:
+			e.ReplaceAttributeValues(f.attr, values)
+		}
+	}
+	return nil
+}
+
+// encodeValue returns the attribute value of v. ok is false if v is empty:
+// a zero value, or a nil pointer or slice. Zero numbers and false are only
+// empty behind a nil pointer, since 0 and FALSE are meaningful values.
+func encodeValue(v reflect.Value) (value string, ok bool, err error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		return encodeValue(v.Elem())
+	}
+
+	switch {
+	case v.Type() == timeType:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", false, nil
+		}
+		return FormatGeneralizedTime(t), true, nil
+	case v.Type() == byteSliceType:
+		return string(v.Bytes()), v.Len() > 0, nil
+	case v.Type().Implements(textMarshalerType):
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err == nil && len(b) > 0, err
+	case v.CanAddr() && v.Addr().Type().Implements(textMarshalerType):
+		b, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err == nil && len(b) > 0, err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), v.Len() > 0, nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "TRUE", true, nil
+		}
+		return "FALSE", true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	}
+	return "", false, fmt.Errorf("unsupported type %s", v.Type())
+}
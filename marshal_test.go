@@ -0,0 +1,187 @@
+package ldapx
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAccount struct {
+	Active bool `ldap:"active"`
+}
+
+type testPerson struct {
+	testAccount
+	DN        string     `ldap:"dn"`
+	CN        string     `ldap:"cn"`
+	Mail      []string   `ldap:"mail,omitempty"`
+	UIDNumber int        `ldap:"uidNumber"`
+	Created   time.Time  `ldap:"createTimestamp,omitempty"`
+	Photo     []byte     `ldap:"jpegPhoto,omitempty"`
+	Address   netip.Addr `ldap:"ipHostNumber,omitempty"`
+	Manager   *string    `ldap:"manager,omitempty"`
+	Ignored   string
+	Skipped   string `ldap:"-"`
+}
+
+func TestEntry_Unmarshal(t *testing.T) {
+	e := NewEntryFromLdapEntry(ldap.NewEntry("uid=jdoe,dc=example,dc=com", map[string][]string{
+		"CN":              {"John Doe"},
+		"mail":            {"jdoe@example.com", "john@example.com"},
+		"uidNumber":       {"1001"},
+		"createTimestamp": {"20240131120000Z"},
+		"jpegPhoto":       {"\xff\xd8\xff"},
+		"ipHostNumber":    {"192.0.2.1"},
+		"manager":         {"uid=boss,dc=example,dc=com"},
+		"active":          {"TRUE"},
+	}))
+
+	p := testPerson{Ignored: "kept"}
+	require.NoError(t, e.Unmarshal(&p))
+	assert.Equal(t, "uid=jdoe,dc=example,dc=com", p.DN)
+	assert.Equal(t, "John Doe", p.CN)
+	assert.Equal(t, []string{"jdoe@example.com", "john@example.com"}, p.Mail)
+	assert.Equal(t, 1001, p.UIDNumber)
+	assert.Equal(t, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC), p.Created)
+	assert.Equal(t, []byte("\xff\xd8\xff"), p.Photo)
+	assert.Equal(t, netip.MustParseAddr("192.0.2.1"), p.Address)
+	require.NotNil(t, p.Manager)
+	assert.Equal(t, "uid=boss,dc=example,dc=com", *p.Manager)
+	assert.True(t, p.Active)
+	assert.Equal(t, "kept", p.Ignored)
+}
+
+func TestEntry_UnmarshalErrors(t *testing.T) {
+	e := NewEntryFromLdapEntry(ldap.NewEntry("uid=jdoe", map[string][]string{"uidNumber": {"many"}}))
+
+	var p testPerson
+	err := e.Unmarshal(&p)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "uidNumber")
+
+	assert.Error(t, e.Unmarshal(p))
+	assert.Error(t, e.Unmarshal((*testPerson)(nil)))
+
+	var bad struct {
+		Values map[string]string `ldap:"values"`
+	}
+	assert.Error(t, e.Unmarshal(&bad))
+}
+
+// Posix is embedded by pointer in testPosixAccount.
+type Posix struct {
+	UIDNumber int    `ldap:"uidNumber"`
+	Home      string `ldap:"homeDirectory,omitempty"`
+}
+
+type testPosixAccount struct {
+	*Posix
+	CN string `ldap:"cn"`
+}
+
+func TestEntry_MarshalEmbeddedPointer(t *testing.T) {
+	e := NewEntryFromLdapEntry(ldap.NewEntry("uid=jdoe", map[string][]string{
+		"cn":            {"John Doe"},
+		"uidNumber":     {"1001"},
+		"homeDirectory": {"/home/jdoe"},
+	}))
+
+	// Unmarshal allocates the embedded struct.
+	var a testPosixAccount
+	require.NoError(t, e.Unmarshal(&a))
+	require.NotNil(t, a.Posix)
+	assert.Equal(t, 1001, a.UIDNumber)
+	assert.Equal(t, "/home/jdoe", a.Home)
+
+	a.UIDNumber = 1002
+	require.NoError(t, e.Marshal(&a))
+	assert.Equal(t, "1002", e.GetAttributeValue("uidNumber"))
+
+	// A nil embedded struct leaves its attributes alone.
+	e.ResetChanges()
+	require.NoError(t, e.Marshal(&testPosixAccount{CN: "John Doe"}))
+	assert.False(t, e.Changed())
+	assert.Equal(t, "1002", e.GetAttributeValue("uidNumber"))
+
+	// The struct is only allocated to set one of its fields.
+	var b testPosixAccount
+	require.NoError(t, NewEntry("uid=jdoe").Unmarshal(&b))
+	assert.Nil(t, b.Posix)
+}
+
+func TestEntry_MarshalEmbeddedPointerErrors(t *testing.T) {
+	type unexported struct {
+		CN string `ldap:"cn"`
+	}
+	var v struct {
+		*unexported
+	}
+	assert.ErrorContains(t, NewEntry("cn=x").Unmarshal(&v), "unexported")
+	_, err := MarshalEntry("cn=x", &v)
+	assert.Error(t, err)
+}
+
+func TestMarshalEntry(t *testing.T) {
+	p := testPerson{
+		DN:        "uid=jdoe,dc=example,dc=com",
+		CN:        "John Doe",
+		UIDNumber: 0,
+		Created:   time.Date(2024, 1, 31, 13, 0, 0, 0, time.FixedZone("", 3600)),
+		Address:   netip.MustParseAddr("192.0.2.1"),
+	}
+
+	e, err := MarshalEntry("", &p)
+	require.NoError(t, err)
+	assert.Equal(t, ChangeAdd, e.ChangeType)
+	assert.Equal(t, "uid=jdoe,dc=example,dc=com", e.DN)
+	assert.Equal(t, "John Doe", e.GetAttributeValue("cn"))
+	assert.Equal(t, "0", e.GetAttributeValue("uidNumber"))
+	assert.Equal(t, "FALSE", e.GetAttributeValue("active"))
+	assert.Equal(t, "20240131120000Z", e.GetAttributeValue("createTimestamp"))
+	assert.Equal(t, "192.0.2.1", e.GetAttributeValue("ipHostNumber"))
+	assert.False(t, e.AttributeExists("mail"))
+	assert.False(t, e.AttributeExists("jpegPhoto"))
+	assert.False(t, e.AttributeExists("manager"))
+
+	e, err = MarshalEntry("uid=other,dc=example,dc=com", p)
+	require.NoError(t, err)
+	assert.Equal(t, "uid=other,dc=example,dc=com", e.DN)
+}
+
+func TestEntry_MarshalChanges(t *testing.T) {
+	e := NewEntryFromLdapEntry(ldap.NewEntry("uid=jdoe,dc=example,dc=com", map[string][]string{
+		"cn":           {"John Doe"},
+		"mail":         {"jdoe@example.com", "john@example.com"},
+		"uidNumber":    {"1001"},
+		"active":       {"TRUE"},
+		"ipHostNumber": {"192.0.2.1"},
+	}))
+
+	var p testPerson
+	require.NoError(t, e.Unmarshal(&p))
+
+	// Writing back what was read changes nothing.
+	require.NoError(t, e.Marshal(&p))
+	assert.False(t, e.Changed())
+
+	p.Mail = []string{"jdoe@example.com", "jd@example.com"}
+	p.UIDNumber = 1002
+	p.Address = netip.Addr{}
+	require.NoError(t, e.Marshal(&p))
+
+	assert.Equal(t, []AttributeChange{
+		{Action: "add", Attr: "mail", Value: []string{"jd@example.com"}},
+		{Action: "delete", Attr: "mail", Value: []string{"john@example.com"}},
+		{Action: "replace", Attr: "uidNumber", Value: []string{"1002"}},
+	}, e.Changes)
+	assert.Equal(t, "uid=jdoe,dc=example,dc=com", e.DN)
+
+	// An empty field without omitempty deletes the attribute.
+	p.CN = ""
+	require.NoError(t, e.Marshal(&p))
+	assert.False(t, e.AttributeExists("cn"))
+}
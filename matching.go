@@ -0,0 +1,213 @@
+package ldapx
+
+import (
+	"math/big"
+	"strings"
+	"time"
+)
+
+// normalizer maps a value to a normal form, so that values that match under
+// an equality matching rule have the same normal form.
+type normalizer func(string) string
+
+// exactValue is the normalizer of values compared octet by octet.
+func exactValue(v string) string {
+	return v
+}
+
+// equalityNormalizers maps the names and OIDs of equality matching rules, in
+// lower case, to their normalizers. Rules that are not listed compare values
+// exactly.
+var equalityNormalizers = map[string]normalizer{}
+
+func init() {
+	rules := []struct {
+		name, oid string
+		normalize normalizer
+	}{
+		{"caseIgnoreMatch", "2.5.13.2", normalizeCaseIgnore},
+		{"caseIgnoreIA5Match", "1.3.6.1.4.1.1466.109.114.2", normalizeCaseIgnore},
+		{"caseIgnoreListMatch", "2.5.13.11", normalizeCaseIgnore},
+		{"caseExactMatch", "2.5.13.5", normalizeSpaces},
+		{"caseExactIA5Match", "1.3.6.1.4.1.1466.109.114.1", normalizeSpaces},
+		{"distinguishedNameMatch", "2.5.13.1", normalizeDN},
+		{"uniqueMemberMatch", "2.5.13.23", normalizeDN},
+		{"telephoneNumberMatch", "2.5.13.20", normalizeTelephoneNumber},
+		{"numericStringMatch", "2.5.13.8", normalizeNumericString},
+		{"integerMatch", "2.5.13.14", normalizeInteger},
+		{"booleanMatch", "2.5.13.13", strings.ToUpper},
+		{"generalizedTimeMatch", "2.5.13.27", normalizeGeneralizedTime},
+		{"objectIdentifierMatch", "2.5.13.0", strings.ToLower},
+		{"octetStringMatch", "2.5.13.17", exactValue},
+	}
+	for _, r := range rules {
+		equalityNormalizers[strings.ToLower(r.name)] = r.normalize
+		equalityNormalizers[r.oid] = r.normalize
+	}
+}
+
+// normalizeSpaces removes leading and trailing spaces and collapses runs of
+// inner spaces, as the insignificant space handling of RFC 4518 does.
+func normalizeSpaces(v string) string {
+	return strings.Join(strings.Fields(v), " ")
+}
+
+// normalizeCaseIgnore normalizes spaces and case.
+func normalizeCaseIgnore(v string) string {
+	return foldCase(normalizeSpaces(v))
+}
+
+// foldCase folds the case of v, so that values equal under strings.EqualFold
+// have the same folded form. Lowercasing alone keeps apart letters such as
+// the final sigma and the long s from the letters they fold to.
+func foldCase(v string) string {
+	return strings.ToLower(strings.ToUpper(v))
+}
+
+// normalizeDN normalizes the attribute types and values of a DN. Values are
+// compared ignoring case, which is how the naming attributes in common use
+// are matched.
+func normalizeDN(v string) string {
+	dn, err := ParseDN(v)
+	if err != nil {
+		return normalizeCaseIgnore(v)
+	}
+	return dn.Normalize(nil).String()
+}
+
+// normalizeTelephoneNumber removes spaces and hyphens and ignores case.
+func normalizeTelephoneNumber(v string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(v))
+}
+
+// normalizeNumericString removes spaces.
+func normalizeNumericString(v string) string {
+	return strings.ReplaceAll(v, " ", "")
+}
+
+// normalizeInteger returns the canonical form of an integer, so that "+1" and
+// "01" match "1". Values that are not integers are left alone.
+func normalizeInteger(v string) string {
+	n, ok := new(big.Int).SetString(strings.TrimSpace(v), 10)
+	if !ok {
+		return v
+	}
+	return n.String()
+}
+
+// normalizeGeneralizedTime converts a generalized time to UTC, so that the
+// same instant in different time zones matches. Values that cannot be parsed
+// are left alone.
+func normalizeGeneralizedTime(v string) string {
+	t, err := ParseGeneralizedTime(v)
+	if err != nil {
+		return v
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// normalizer returns the normalizer of the equality matching rule of the
+// attribute. Attributes without a known rule are compared exactly.
+func (s *LDAPSchema) normalizer(attr string) normalizer {
+	at := s.AttributeType(attr)
+	if at == nil || at.Equality == "" {
+		return exactValue
+	}
+
+	rule := strings.ToLower(at.Equality)
+	if n, ok := equalityNormalizers[rule]; ok {
+		return s.resolveOIDs(rule, n)
+	}
+	// The rule may be given by an OID or a name that is only in the schema.
+	if mr := s.MatchingRule(rule); mr != nil {
+		if n, ok := equalityNormalizers[mr.OID]; ok {
+			return s.resolveOIDs(mr.OID, n)
+		}
+	}
+	return exactValue
+}
+
+// resolveOIDs makes objectIdentifierMatch treat the names of object classes
+// and attribute types in the schema as their OIDs.
+func (s *LDAPSchema) resolveOIDs(rule string, n normalizer) normalizer {
+	if rule != "objectidentifiermatch" && rule != "2.5.13.0" {
+		return n
+	}
+	return func(v string) string {
+		if oc := s.ObjectClass(v); oc != nil {
+			return strings.ToLower(oc.OID)
+		}
+		if at := s.AttributeType(v); at != nil {
+			return strings.ToLower(at.OID)
+		}
+		return n(v)
+	}
+}
+
+// NormalizeValue returns the normal form of the value under the equality
+// matching rule of the attribute. Values of attributes without a known rule
+// are returned as they are.
+func (s *LDAPSchema) NormalizeValue(attr, value string) string {
+	return s.normalizer(attr)(value)
+}
+
+// NormalizeValueByRule returns the normal form of the value under the given
+// equality matching rule, by name or OID. It returns false if the rule is not
+// known.
+func NormalizeValueByRule(rule, value string) (string, bool) {
+	n, ok := equalityNormalizers[strings.ToLower(rule)]
+	if !ok {
+		return "", false
+	}
+	return n(value), true
+}
+
+// ValuesMatch returns true if the two values of the attribute match under its
+// equality matching rule.
+func (s *LDAPSchema) ValuesMatch(attr, a, b string) bool {
+	n := s.normalizer(attr)
+	return n(a) == n(b)
+}
+
+// BindSchema binds the entry to the schema. The mutators of a bound entry
+// compare values with the equality matching rule of each attribute, as the
+// server does, unless they are asked to ignore case. A nil schema unbinds
+// the entry.
+func (e *Entry) BindSchema(schema *LDAPSchema) *Entry {
+	e.schema = schema
+	return e
+}
+
+// Schema returns the schema the entry is bound to, or nil.
+func (e *Entry) Schema() *LDAPSchema {
+	return e.schema
+}
+
+// WithSchemaMatching binds the entries returned by Lookup, LookupOrNew,
+// FindEntry and UpdateEntry to the server schema.
+func WithSchemaMatching() Option {
+	return func(c *config) {
+		c.schemaMatching = true
+	}
+}
+
+// valueNormalizer returns the normalizer used by the mutators for values of
+// the attribute: case is ignored if ignoreCase is true; otherwise the equality
+// matching rule is used if the entry is bound to a schema.
+func (e *Entry) valueNormalizer(attr string, ignoreCase bool) normalizer {
+	switch {
+	case ignoreCase:
+		return foldCase
+	case e.schema != nil:
+		return e.schema.normalizer(attr)
+	}
+	return exactValue
+}
+
+// bindEntry binds the entry to the schema if schema matching is enabled.
+func (c *Conn) bindEntry(e *Entry) *Entry {
+	if c.schemaMatching && e != nil {
+		e.BindSchema(c.schema)
+	}
+	return e
+}
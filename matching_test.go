@@ -0,0 +1,176 @@
+package ldapx
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDAPSchema_ValuesMatch(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+
+	tests := []struct {
+		attr  string
+		a, b  string
+		match bool
+	}{
+		{"cn", "John  Doe", " john doe ", true},
+		{"cn", "John Doe", "Jon Doe", false},
+		{"homeDirectory", "/home/jdoe", "/HOME/jdoe", false},
+		{"homeDirectory", "/home/jdoe", "/home/jdoe", true},
+		{"member", "CN=Admins, OU=Groups,DC=Example,DC=com", "cn=admins,ou=groups,dc=example,dc=com", true},
+		{"member", "cn=admins,ou=groups,dc=example,dc=com", "cn=users,ou=groups,dc=example,dc=com", false},
+		{"telephoneNumber", "+1 555-0100", "+15550100", true},
+		{"uidNumber", "0100", "100", true},
+		{"uidNumber", "100", "101", false},
+		{"objectClass", "person", "2.5.6.6", true},
+		{"objectClass", "Person", "PERSON", true},
+		{"createTimestamp", "20240131120000Z", "20240131130000+0100", true},
+		{"userPassword", "secret", "SECRET", false},
+		{"unknownAttribute", "a", "A", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.attr+"/"+tt.a, func(t *testing.T) {
+			assert.Equal(t, tt.match, schema.ValuesMatch(tt.attr, tt.a, tt.b))
+		})
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+	assert.Equal(t, "john doe", schema.NormalizeValue("cn", " John  Doe"))
+	assert.Equal(t, "Secret", schema.NormalizeValue("userPassword", "Secret"))
+
+	v, ok := NormalizeValueByRule("integerMatch", "+0100")
+	assert.True(t, ok)
+	assert.Equal(t, "100", v)
+	v, ok = NormalizeValueByRule("2.5.13.2", "ABC")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", v)
+	_, ok = NormalizeValueByRule("unknownMatch", "x")
+	assert.False(t, ok)
+
+	e := NewEntry("cn=test")
+	assert.Nil(t, e.Schema())
+	assert.Same(t, schema, e.BindSchema(schema).Schema())
+}
+
+func TestEntry_BindSchema(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+
+	entry := NewEntryFromLdapEntry(ldap.NewEntry("cn=admins,ou=groups,dc=example,dc=com", map[string][]string{
+		"cn":              {"Admins"},
+		"member":          {"uid=jdoe,ou=people,dc=example,dc=com", "uid=asmith,ou=people,dc=example,dc=com"},
+		"homeDirectory":   {"/home/admins"},
+		"telephoneNumber": {"+1 555-0100"},
+	})).BindSchema(schema)
+
+	// Values that match are not added again.
+	entry.AddAttributeValue("cn", "ADMINS")
+	entry.AddAttributeValue("member", "UID=jdoe, OU=People, DC=example, DC=com")
+	entry.AddAttributeValue("telephoneNumber", "+15550100")
+	assert.False(t, entry.Changed())
+
+	// caseExactIA5Match keeps values that differ in case.
+	entry.AddAttributeValue("homeDirectory", "/HOME/admins")
+	assert.Equal(t, []AttributeChange{{Action: "add", Attr: "homeDirectory", Value: []string{"/HOME/admins"}}}, entry.Changes)
+	entry.ResetChanges()
+
+	// Deletes match values like the server.
+	entry.DeleteAttributeValue("member", "uid=ASMITH,ou=people,dc=example,dc=com")
+	assert.Equal(t, []string{"uid=jdoe,ou=people,dc=example,dc=com"}, entry.GetAttributeValues("member"))
+	assert.Equal(t, []string{"uid=asmith,ou=people,dc=example,dc=com"}, entry.Changes[0].Value)
+	entry.ResetChanges()
+
+	// Replacing with matching values is a no-op.
+	entry.ReplaceAttributeValue("cn", "admins")
+	assert.False(t, entry.Changed())
+
+	// Sync only adds and removes values that do not match.
+	entry.SyncAttributeValues("member", []string{"UID=JDOE,OU=PEOPLE,DC=EXAMPLE,DC=COM", "uid=bjones,ou=people,dc=example,dc=com"})
+	assert.Equal(t, []AttributeChange{{Action: "add", Attr: "member", Value: []string{"uid=bjones,ou=people,dc=example,dc=com"}}}, entry.Changes)
+
+	// New attributes are deduplicated.
+	entry.ResetChanges()
+	entry.AddAttributeValues("description", []string{"Admin group", "admin  group"})
+	assert.Equal(t, []string{"Admin group"}, entry.GetAttributeValues("description"))
+}
+
+func TestEntry_BindSchemaIgnoreCase(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+
+	entry := NewEntry("cn=test").BindSchema(schema)
+	entry.AddAttributeValue("homeDirectory", "/home/test")
+
+	// Asking to ignore case overrides the matching rule.
+	entry.AddAttributeValueIgnoreCase("homeDirectory", "/HOME/TEST", true)
+	assert.Equal(t, []string{"/home/test"}, entry.GetAttributeValues("homeDirectory"))
+}
+
+func TestEntry_IgnoreCaseFolding(t *testing.T) {
+	// Values equal under strings.EqualFold are the same when ignoring case,
+	// even where lowercasing them gives different strings.
+	entry := NewEntry("cn=test")
+	entry.AddAttributeValue("description", "οδος")
+	entry.AddAttributeValue("description", "ſtreet")
+	entry.AddAttributeValueIgnoreCase("description", "ΟΔΟΣ", true)
+	entry.AddAttributeValueIgnoreCase("description", "STREET", true)
+	assert.Equal(t, []string{"οδος", "ſtreet"}, entry.GetAttributeValues("description"))
+
+	entry.DeleteAttributeValueIgnoreCase("description", "Street", true)
+	assert.Equal(t, []string{"οδος"}, entry.GetAttributeValues("description"))
+	assert.Equal(t, normalizeCaseIgnore("ΟΔΟΣ"), normalizeCaseIgnore("οδος"))
+}
+
+func TestConn_WithSchemaMatching(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=subschema", map[string][]string{
+		"objectClass":    {"subschema"},
+		"attributeTypes": {"( 2.5.4.3 NAME 'cn' EQUALITY caseIgnoreMatch )"},
+	})
+	s.put("cn=test,dc=example,dc=com", map[string][]string{"cn": {"Test"}, "objectClass": {"person"}})
+
+	conn, err := Open(s.url(), WithSchemaMatching())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.UpdateEntry("cn=test,dc=example,dc=com", func(e *Entry) (*Entry, error) {
+		e.AddAttributeValue("cn", "TEST")
+		return e, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), s.writes.Load())
+}
+
+func TestParseGeneralizedTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"20240131120000Z", "2024-01-31T12:00:00Z"},
+		{"20240131120000.5Z", "2024-01-31T12:00:00.5Z"},
+		{"202401311200Z", "2024-01-31T12:00:00Z"},
+		{"2024013112,25Z", "2024-01-31T12:15:00Z"},
+		{"20240131130000+0100", "2024-01-31T12:00:00Z"},
+		{"20240131120000", "2024-01-31T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		got, err := ParseGeneralizedTime(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got.UTC().Format("2006-01-02T15:04:05.999999999Z07:00"), tt.value)
+	}
+
+	invalid := []string{
+		"", "2024", "20241331120000Z", "20240131120000.Z",
+		"20240131120000.5xZ", "20240131120000.5 Z", "20240131120000.5e1Z",
+		"20240131120000.5junk", "20240131120000.5Z+0100", "20240131120000.-5Z",
+		"20240131120000.5+01x0",
+	}
+	for _, value := range invalid {
+		_, err := ParseGeneralizedTime(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,120 @@
+package ldapx
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// Option configures a Conn opened with Open.
+type Option func(*config)
+
+// config holds the settings used to open a Conn.
+type config struct {
+	auth           Authenticator     // Authenticator used to bind pooled connections
+	tlsConfig      *tls.Config       // TLS configuration used when dialing
+	clientCerts    []tls.Certificate // Client certificates added to the TLS configuration
+	startTLS       StartTLSMode      // StartTLS mode for ldap:// URLs
+	dialTimeout    time.Duration     // Maximum time to connect to a server (0 means no limit)
+	servers        []string          // Additional server URLs
+	serverPolicy   ServerPolicy      // Order in which servers are dialed
+	probeInterval  time.Duration     // How often down servers are probed
+	readServers    []string          // Servers used for reads
+	readYourWrites time.Duration     // How long reads of a written DN go to the write servers
+	retry          RetryPolicy       // How failed operations are retried
+	validate       bool              // Validate entries against the schema in Update
+	schemaMatching bool              // Bind looked up entries to the schema
+	pool           poolConfig        // Connection pool settings
+}
+
+// poolConfig holds the connection pool settings.
+type poolConfig struct {
+	initialCap  int           // Number of connections opened when the pool is created
+	maxIdle     int           // Maximum number of idle connections kept in the pool
+	maxCap      int           // Maximum number of open connections
+	idleTimeout time.Duration // Idle connections older than this are closed
+	maxLifetime time.Duration // Connections older than this are closed (0 means no limit)
+	getTimeout  time.Duration // Maximum time to wait for a pooled connection (0 means wait forever)
+	pingOnReuse bool          // Ping idle connections before handing them out
+}
+
+// defaultConfig returns the default settings.
+func defaultConfig() *config {
+	return &config{
+		auth:          SimpleAuth{},
+		dialTimeout:   10 * time.Second,
+		probeInterval: 30 * time.Second,
+		pool: poolConfig{
+			initialCap:  0,
+			maxIdle:     1,
+			maxCap:      10,
+			idleTimeout: 60 * time.Second,
+			pingOnReuse: true,
+		},
+	}
+}
+
+// WithBind sets the DN and password used to bind pooled connections. An empty
+// password results in an unauthenticated bind.
+func WithBind(bindDN, bindPassword string) Option {
+	return func(c *config) {
+		c.auth = SimpleAuth{DN: bindDN, Password: bindPassword}
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used when dialing the server.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithDialTimeout sets how long to wait for a server to accept a connection,
+// including the TLS handshake on ldaps:// URLs. Zero waits as long as the
+// operating system does.
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.dialTimeout = d
+	}
+}
+
+// WithPoolSize sets the number of connections opened up front, the maximum
+// number of idle connections and the maximum number of open connections.
+func WithPoolSize(initialCap, maxIdle, maxCap int) Option {
+	return func(c *config) {
+		c.pool.initialCap = initialCap
+		c.pool.maxIdle = maxIdle
+		c.pool.maxCap = maxCap
+	}
+}
+
+// WithIdleTimeout sets how long a connection may sit idle in the pool before
+// it is closed.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.pool.idleTimeout = d
+	}
+}
+
+// WithMaxLifetime sets the maximum age of a pooled connection. Older
+// connections are closed instead of being reused. Zero disables the limit.
+func WithMaxLifetime(d time.Duration) Option {
+	return func(c *config) {
+		c.pool.maxLifetime = d
+	}
+}
+
+// WithGetTimeout sets how long to wait for a connection when the pool is
+// exhausted. Zero waits forever.
+func WithGetTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.pool.getTimeout = d
+	}
+}
+
+// WithPingOnReuse sets whether idle connections are pinged before they are
+// handed out.
+func WithPingOnReuse(ping bool) Option {
+	return func(c *config) {
+		c.pool.pingOnReuse = ping
+	}
+}
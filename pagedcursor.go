@@ -0,0 +1,138 @@
+package ldapx
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// PagedCursor reads the results of a paged search one page at a time. The
+// cookie of the next page can be saved with Cookie and handed to Resume, so
+// that a long export can checkpoint its progress.
+//
+// A cursor holds one connection from the pool between pages, since most
+// servers only accept a cookie on the connection that issued it. Close must
+// be called to return the connection.
+type PagedCursor struct {
+	conn   *Conn              // Client the cursor was created from
+	lc     *ldap.Conn         // Connection holding the paged search, nil until the first page
+	pager  *pager             // Paging state of the search
+	done   bool               // done is true once the last page was read
+	result *ldap.SearchResult // Last page, for referrals and controls
+}
+
+// NewPagedCursor returns a cursor over the results of the search, in pages of
+// pageSize entries, or 1000 if pageSize is 0. No request is sent until Next
+// is called.
+func (c *Conn) NewPagedCursor(request *ldap.SearchRequest, pageSize uint32) *PagedCursor {
+	if pageSize == 0 {
+		pageSize = 1000
+	}
+	return &PagedCursor{
+		conn:  c,
+		pager: newPager(request, pageSize),
+	}
+}
+
+// More returns true until the last page has been read.
+func (p *PagedCursor) More() bool {
+	return !p.done
+}
+
+// Cookie returns the opaque cookie of the next page. It is empty before the
+// first page and after the last one.
+func (p *PagedCursor) Cookie() []byte {
+	return bytes.Clone(p.pager.cookie())
+}
+
+// Resume makes the next call to Next continue from the given cookie, as
+// returned by Cookie. The search request and page size must be the same as
+// those of the search the cookie came from. Servers that tie cookies to a
+// connection, such as OpenLDAP, only accept a cookie on the cursor that
+// returned it; others, such as Active Directory, also accept it on a new one.
+func (p *PagedCursor) Resume(cookie []byte) {
+	p.pager.paging.SetCookie(bytes.Clone(cookie))
+	p.done = false
+}
+
+// Referrals returns the referrals returned with the last page.
+func (p *PagedCursor) Referrals() []string {
+	if p.result == nil {
+		return nil
+	}
+	return p.result.Referrals
+}
+
+// Controls returns the controls returned with the last page.
+func (p *PagedCursor) Controls() []ldap.Control {
+	if p.result == nil {
+		return nil
+	}
+	return p.result.Controls
+}
+
+// Next returns the next page of entries. It returns no entries once the last
+// page has been read. After an error the cookie is kept, so Next can be
+// called again to retry the page.
+func (p *PagedCursor) Next() ([]*Entry, error) {
+	return p.NextContext(context.Background())
+}
+
+// NextContext returns the next page of entries. It returns no entries once
+// the last page has been read. After an error the cookie is kept, so
+// NextContext can be called again to retry the page.
+func (p *PagedCursor) NextContext(ctx context.Context) ([]*Entry, error) {
+	if p.done {
+		return nil, nil
+	}
+
+	baseDN := p.pager.request.BaseDN
+	if p.lc == nil {
+		lc, err := p.conn.getRead(ctx, baseDN)
+		if err != nil {
+			return nil, wrapError("search", baseDN, err)
+		}
+		p.lc = lc
+	}
+
+	result, err, broken := p.conn.searchPage(ctx, p.lc, p.pager.request)
+	if err != nil {
+		if broken {
+			p.conn.discard(p.lc)
+			p.lc = nil
+		}
+		return nil, wrapError("search", baseDN, err)
+	}
+	p.result = result
+
+	if !p.pager.next(result) {
+		p.done = true
+		p.conn.put(p.lc)
+		p.lc = nil
+	}
+
+	entries := make([]*Entry, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		entries = append(entries, NewEntryFromLdapEntry(e))
+	}
+	return entries, nil
+}
+
+// Close abandons the paged search if it has more pages and returns the
+// connection to the pool. The cookie is no longer valid afterwards.
+func (p *PagedCursor) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+// CloseContext abandons the paged search if it has more pages and returns
+// the connection to the pool. The cookie is no longer valid afterwards.
+func (p *PagedCursor) CloseContext(ctx context.Context) error {
+	p.done = true
+	if p.lc == nil {
+		return nil
+	}
+	err := p.conn.closePager(ctx, p.lc, p.pager)
+	p.lc = nil
+	return wrapError("search", p.pager.request.BaseDN, err)
+}
@@ -0,0 +1,87 @@
+package ldapx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagedCursor(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 25)
+
+	conn, err := Open(s.url(), WithPoolSize(0, 1, 1), WithGetTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=people,dc=example,dc=com", ldap.ScopeSingleLevel, ldap.DerefAlways, 0, 0, false, "(objectClass=person)", nil, nil)
+	cursor := conn.NewPagedCursor(request, 10)
+	defer cursor.Close()
+
+	var sizes []int
+	for cursor.More() {
+		entries, err := cursor.Next()
+		require.NoError(t, err)
+		sizes = append(sizes, len(entries))
+	}
+	assert.Equal(t, []int{10, 10, 5}, sizes)
+	assert.Empty(t, cursor.Cookie())
+
+	// The connection is returned after the last page.
+	_, err = conn.Lookup("ou=people,dc=example,dc=com")
+	assert.NoError(t, err)
+}
+
+func TestPagedCursor_Resume(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 25)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=people,dc=example,dc=com", ldap.ScopeSingleLevel, ldap.DerefAlways, 0, 0, false, "(objectClass=person)", nil, nil)
+	cursor := conn.NewPagedCursor(request, 10)
+	defer cursor.Close()
+
+	_, err = cursor.Next()
+	require.NoError(t, err)
+	checkpoint := cursor.Cookie()
+	require.NotEmpty(t, checkpoint)
+
+	second, err := cursor.Next()
+	require.NoError(t, err)
+
+	cursor.Resume(checkpoint)
+	again, err := cursor.Next()
+	require.NoError(t, err)
+	require.Len(t, again, 10)
+	assert.Equal(t, second[0].DN, again[0].DN)
+	assert.Equal(t, "user10", again[0].GetAttributeValue("uid"))
+}
+
+func TestPagedCursor_CloseEarly(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 25)
+
+	conn, err := Open(s.url(), WithPoolSize(0, 1, 1), WithGetTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=people,dc=example,dc=com", ldap.ScopeSingleLevel, ldap.DerefAlways, 0, 0, false, "(objectClass=person)", nil, nil)
+	cursor := conn.NewPagedCursor(request, 10)
+
+	_, err = cursor.Next()
+	require.NoError(t, err)
+	require.NoError(t, cursor.Close())
+
+	assert.Equal(t, int32(1), s.abandons.Load())
+	assert.False(t, cursor.More())
+	assert.Empty(t, cursor.Cookie())
+
+	_, err = conn.Lookup("ou=people,dc=example,dc=com")
+	assert.NoError(t, err)
+}
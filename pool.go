@@ -0,0 +1,233 @@
+package ldapx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/silenceper/pool"
+)
+
+// connPool is a pool of bound LDAP connections.
+type connPool struct {
+	pool        pool.Pool               // Underlying channel pool
+	slots       chan struct{}           // Holds a token for each connection handed out, up to the maximum
+	closed      chan struct{}           // Closed when the pool is released
+	closeOnce   sync.Once               // Closes closed once
+	getTimeout  time.Duration           // Maximum time to wait for a connection
+	maxLifetime time.Duration           // Maximum age of a connection
+	mu          sync.Mutex              // Protects conns
+	conns       map[*ldap.Conn]connInfo // Details of each open connection
+}
+
+// connInfo holds the details of a pooled connection.
+type connInfo struct {
+	created time.Time // When the connection was opened
+	server  *server   // Server the connection was dialed to
+}
+
+// newConnPool creates a connection pool that uses factory to open new connections.
+func newConnPool(cfg poolConfig, factory func() (*ldap.Conn, *server, error)) (*connPool, error) {
+	p := &connPool{
+		getTimeout:  cfg.getTimeout,
+		maxLifetime: cfg.maxLifetime,
+		conns:       make(map[*ldap.Conn]connInfo),
+	}
+
+	pcfg := &pool.Config{
+		InitialCap:  cfg.initialCap,
+		MaxIdle:     cfg.maxIdle,
+		MaxCap:      cfg.maxCap,
+		IdleTimeout: cfg.idleTimeout,
+		Factory: func() (interface{}, error) {
+			conn, srv, err := factory()
+			if err != nil {
+				return nil, err
+			}
+			p.mu.Lock()
+			p.conns[conn] = connInfo{created: time.Now(), server: srv}
+			p.mu.Unlock()
+			return conn, nil
+		},
+		Close: func(conn interface{}) error {
+			lc := conn.(*ldap.Conn)
+			p.mu.Lock()
+			delete(p.conns, lc)
+			p.mu.Unlock()
+			return lc.Close()
+		},
+	}
+
+	// The pool only checks idle connections when a ping function is set, so
+	// the lifetime check piggybacks on it.
+	if cfg.pingOnReuse || cfg.maxLifetime > 0 {
+		pcfg.Ping = func(conn interface{}) error {
+			lc := conn.(*ldap.Conn)
+			if p.expired(lc) {
+				return errors.New("connection exceeded its maximum lifetime")
+			}
+			if !cfg.pingOnReuse {
+				return nil
+			}
+			err := pingConnection(lc)
+			if srv := p.server(lc); err != nil && srv != nil {
+				srv.down.Store(true)
+			}
+			return err
+		}
+	}
+
+	pl, err := pool.NewChannelPool(pcfg)
+	if err != nil {
+		return nil, fmt.Errorf("create connection pool error: %w", err)
+	}
+	p.pool = pl
+	p.slots = make(chan struct{}, cfg.maxCap)
+	p.closed = make(chan struct{})
+
+	return p, nil
+}
+
+// expired returns true if the connection is older than the maximum lifetime.
+func (p *connPool) expired(lc *ldap.Conn) bool {
+	if p.maxLifetime <= 0 {
+		return false
+	}
+	p.mu.Lock()
+	info, ok := p.conns[lc]
+	p.mu.Unlock()
+	return ok && time.Since(info.created) > p.maxLifetime
+}
+
+// owns returns true if the connection was opened by this pool.
+func (p *connPool) owns(lc *ldap.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.conns[lc]
+	return ok
+}
+
+// server returns the server the connection was dialed to, or nil if the
+// connection is not from this pool.
+func (p *connPool) server(lc *ldap.Conn) *server {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conns[lc].server
+}
+
+// get gets a connection from the pool, waiting at most getTimeout or until
+// the context is done. A slot is taken for each connection handed out, so
+// that the underlying pool, whose Get cannot be interrupted, never waits for
+// a connection to be returned; it only dials or pings one.
+func (p *connPool) get(ctx context.Context) (*ldap.Conn, error) {
+	if p.getTimeout <= 0 && ctx.Done() == nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-p.closed:
+			return nil, pool.ErrClosed
+		}
+		return p.takeSlot()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var timeout <-chan time.Time
+	if p.getTimeout > 0 {
+		timer := time.NewTimer(p.getTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-timeout:
+		return nil, ErrPoolTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.closed:
+		return nil, pool.ErrClosed
+	}
+
+	type result struct {
+		conn *ldap.Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		lc, err := p.takeSlot()
+		ch <- result{lc, err}
+	}()
+
+	var err error
+	select {
+	case r := <-ch:
+		return r.conn, r.err
+	case <-timeout:
+		err = ErrPoolTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// Hand the connection back once the dial or ping completes.
+	go func() {
+		if r := <-ch; r.err == nil {
+			p.put(r.conn)
+		}
+	}()
+	return nil, err
+}
+
+// takeSlot gets a connection from the underlying pool once a slot is taken,
+// freeing the slot if that fails.
+func (p *connPool) takeSlot() (*ldap.Conn, error) {
+	lc, err := p.take()
+	if err != nil {
+		p.done()
+		return nil, err
+	}
+	return lc, nil
+}
+
+// done frees the slot of a connection that was handed out.
+func (p *connPool) done() {
+	select {
+	case <-p.slots:
+	default:
+	}
+}
+
+// take gets a connection from the underlying pool.
+func (p *connPool) take() (*ldap.Conn, error) {
+	lc, err := p.pool.Get()
+	if err != nil {
+		return nil, err
+	}
+	return lc.(*ldap.Conn), nil
+}
+
+// put returns a connection to the pool. Closed or expired connections are
+// discarded instead.
+func (p *connPool) put(lc *ldap.Conn) {
+	if lc.IsClosing() || p.expired(lc) {
+		p.discard(lc)
+		return
+	}
+	_ = p.pool.Put(lc)
+	p.done()
+}
+
+// discard closes a connection and removes it from the pool.
+func (p *connPool) discard(lc *ldap.Conn) {
+	_ = p.pool.Close(lc)
+	p.done()
+}
+
+// release closes all connections in the pool.
+func (p *connPool) release() {
+	p.closeOnce.Do(func() { close(p.closed) })
+	p.pool.Release()
+}
@@ -0,0 +1,123 @@
+package ldapx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_Defaults(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rootDSE, err := conn.RootDSE()
+	require.NoError(t, err)
+	assert.Equal(t, "cn=subschema", rootDSE.SubschemaSubEntry)
+	assert.EqualValues(t, 1, s.dials.Load())
+}
+
+func TestOpen_GetTimeout(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithPoolSize(0, 1, 1), WithGetTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	lc, err := conn.get(context.Background())
+	require.NoError(t, err)
+
+	_, err = conn.get(context.Background())
+	assert.True(t, errors.Is(err, ErrPoolTimeout))
+
+	conn.put(lc)
+	lc, err = conn.get(context.Background())
+	require.NoError(t, err)
+	conn.put(lc)
+}
+
+func TestOpen_MaxLifetime(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithMaxLifetime(20*time.Millisecond), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	time.Sleep(40 * time.Millisecond)
+
+	_, err = conn.RootDSE()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, s.dials.Load())
+}
+
+func TestOpenURL_Bind(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := OpenURL(s.url(), "cn=admin,dc=example,dc=com", "secret", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.EqualValues(t, 1, s.binds.Load())
+}
+
+func TestOpen_DialTimeout(t *testing.T) {
+	// The listener accepts connections but never completes a TLS handshake.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	start := time.Now()
+	_, err = Open("ldaps://"+l.Addr().String(), WithDialTimeout(100*time.Millisecond))
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestOpen_GetTimeoutDoesNotLeak(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithPoolSize(0, 1, 1), WithGetTimeout(5*time.Millisecond))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	lc, err := conn.get(context.Background())
+	require.NoError(t, err)
+
+	before := runtime.NumGoroutine()
+	for range 20 {
+		_, err = conn.get(context.Background())
+		assert.True(t, errors.Is(err, ErrPoolTimeout))
+
+		// The context may be canceled before or after the pool times out.
+		ctx, cancel := context.WithCancel(context.Background())
+		timer := time.AfterFunc(time.Millisecond, cancel)
+		_, err = conn.get(ctx)
+		assert.True(t, errors.Is(err, context.Canceled) || errors.Is(err, ErrPoolTimeout), "%v", err)
+		timer.Stop()
+		cancel()
+	}
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before+2
+	}, time.Second, 10*time.Millisecond)
+
+	conn.put(lc)
+	lc, err = conn.get(context.Background())
+	require.NoError(t, err)
+	conn.put(lc)
+}
@@ -0,0 +1,132 @@
+package ldapx
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// WithReadServers sends searches, lookups, compares and RootDSE reads to a
+// separate pool of connections to the given servers, typically read-only
+// consumer replicas. Writes keep going to the servers given to Open.
+func WithReadServers(urls ...string) Option {
+	return func(c *config) {
+		c.readServers = append(c.readServers, urls...)
+	}
+}
+
+// WithReadYourWrites sends reads of a DN to the write servers for the given
+// duration after the DN was written, so that callers see their own changes
+// before they have replicated. It has no effect without WithReadServers.
+func WithReadYourWrites(d time.Duration) Option {
+	return func(c *config) {
+		c.readYourWrites = d
+	}
+}
+
+// stickyDNs records recently written DNs.
+type stickyDNs struct {
+	duration time.Duration        // How long a written DN stays sticky
+	mu       sync.Mutex           // Protects written
+	written  map[string]time.Time // Expiry time keyed by lowercased DN
+}
+
+// newStickyDNs creates a set of sticky DNs that expire after d.
+func newStickyDNs(d time.Duration) *stickyDNs {
+	return &stickyDNs{duration: d, written: make(map[string]time.Time)}
+}
+
+// touch records a write to the DN.
+func (s *stickyDNs) touch(dn string) {
+	if s == nil || s.duration <= 0 || dn == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, expires := range s.written {
+		if now.After(expires) {
+			delete(s.written, k)
+		}
+	}
+	s.written[strings.ToLower(dn)] = now.Add(s.duration)
+}
+
+// has returns true if the DN was written recently.
+func (s *stickyDNs) has(dn string) bool {
+	if s == nil || s.duration <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.written[strings.ToLower(dn)]
+	return ok && time.Now().Before(expires)
+}
+
+// getRead gets a connection for reading the given DN. It comes from the read
+// pool unless there is none or the DN was written recently.
+func (c *Conn) getRead(ctx context.Context, dn string) (*ldap.Conn, error) {
+	if c.txConn != nil || c.readPool == nil || c.sticky.has(dn) {
+		return c.get(ctx)
+	}
+	lc, err := getConn(ctx, c.readPool)
+	return lc, wrapError("connect", "", err)
+}
+
+// executeRead executes the read operation op with a connection for reading
+// the given DN, retrying transient failures.
+func (c *Conn) executeRead(ctx context.Context, op, dn string, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	result, err := c.withRetry(ctx, true, func() (interface{}, error) {
+		conn, err := c.getRead(ctx, dn)
+		if err != nil {
+			return nil, err
+		}
+		return c.run(ctx, conn, f)
+	})
+	return result, wrapError(op, dn, err)
+}
+
+// executeWrite executes the write operation op on the given DN, retrying
+// transient failures if the write is idempotent and recording the write for
+// read-your-writes.
+func (c *Conn) executeWrite(ctx context.Context, op, dn string, idempotent bool, f func(*ldap.Conn) (interface{}, error)) (interface{}, error) {
+	result, err := c.withRetry(ctx, idempotent, func() (interface{}, error) {
+		return c.execute(ctx, f)
+	})
+	if err == nil {
+		c.sticky.touch(dn)
+	}
+	return result, wrapError(op, dn, err)
+}
+
+// renamedDN returns the DN of the entry once the modify DN request is done,
+// or "" if the DN of the request cannot be parsed.
+func renamedDN(request *ldap.ModifyDNRequest) string {
+	parent := request.NewSuperior
+	if parent == "" {
+		dn, err := ParseDN(request.DN)
+		if err != nil {
+			return ""
+		}
+		parent = dn.Parent().String()
+	}
+	if parent == "" {
+		return request.NewRDN
+	}
+	return request.NewRDN + "," + parent
+}
+
+// poolOf returns the pool a connection belongs to.
+func (c *Conn) poolOf(lc *ldap.Conn) *connPool {
+	if c.readPool != nil && c.readPool.owns(lc) {
+		return c.readPool
+	}
+	return c.pool
+}
@@ -0,0 +1,103 @@
+package ldapx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_ReadWriteSplit(t *testing.T) {
+	provider := newFakeServer(t)
+	consumer := newFakeServer(t)
+
+	conn, err := Open(provider.url(), WithReadServers(consumer.url()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The schema is read from the consumer.
+	assert.EqualValues(t, 0, provider.searches.Load())
+
+	err = conn.UpdateEntry("ou=people,dc=example,dc=com", func(e *Entry) (*Entry, error) {
+		e.AddAttributeValues("objectClass", []string{"organizationalUnit"})
+		return e, nil
+	})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, provider.writes.Load())
+	assert.EqualValues(t, 0, consumer.writes.Load())
+	assert.EqualValues(t, 0, provider.searches.Load())
+
+	// Without read-your-writes the lookup goes to the consumer, which has
+	// not seen the entry.
+	_, err = conn.Lookup("ou=people,dc=example,dc=com")
+	assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject))
+}
+
+func TestOpen_ReadYourWrites(t *testing.T) {
+	provider := newFakeServer(t)
+	consumer := newFakeServer(t)
+
+	conn, err := Open(provider.url(), WithReadServers(consumer.url()), WithReadYourWrites(time.Minute), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.UpdateEntry("ou=people,dc=example,dc=com", func(e *Entry) (*Entry, error) {
+		e.AddAttributeValues("objectClass", []string{"organizationalUnit"})
+		return e, nil
+	})
+	require.NoError(t, err)
+
+	entry, err := conn.Lookup("OU=People,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.Equal(t, "organizationalUnit", entry.GetAttributeValue("objectClass"))
+	assert.EqualValues(t, 1, provider.searches.Load())
+
+	// Other DNs are still read from the consumer.
+	_, err = conn.Lookup("")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, provider.searches.Load())
+}
+
+func TestOpen_ReadYourWritesRename(t *testing.T) {
+	provider := newFakeServer(t)
+	consumer := newFakeServer(t)
+	provider.put("uid=jdoe,ou=people,dc=example,dc=com", map[string][]string{"objectClass": {"person"}, "uid": {"jdoe"}})
+
+	conn, err := Open(provider.url(), WithReadServers(consumer.url()), WithReadYourWrites(time.Minute), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+	searches := consumer.searches.Load()
+
+	// The renamed entry is read from the provider until it has replicated.
+	err = conn.ModifyDN(NewModifyDNRequest("uid=jdoe,ou=people,dc=example,dc=com", "uid=john", true, "", nil))
+	require.NoError(t, err)
+	entry, err := conn.Lookup("uid=john,ou=people,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe", entry.GetAttributeValue("uid"))
+
+	// So is an entry moved under a new parent.
+	err = conn.ModifyDN(NewModifyDNRequest("uid=john,ou=people,dc=example,dc=com", "uid=john", false, "ou=staff,dc=example,dc=com", nil))
+	require.NoError(t, err)
+	_, err = conn.Lookup("uid=john,ou=staff,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, provider.searches.Load())
+	assert.Equal(t, searches, consumer.searches.Load())
+}
+
+func TestRenamedDN(t *testing.T) {
+	assert.Equal(t, "cn=b,dc=example,dc=com", renamedDN(NewModifyDNRequest("cn=a,dc=example,dc=com", "cn=b", true, "", nil)))
+	assert.Equal(t, "cn=a,ou=x", renamedDN(NewModifyDNRequest("cn=a,dc=example,dc=com", "cn=a", true, "ou=x", nil)))
+	assert.Equal(t, "cn=b", renamedDN(NewModifyDNRequest("cn=a", "cn=b", true, "", nil)))
+}
+
+func TestStickyDNs_Expire(t *testing.T) {
+	s := newStickyDNs(10 * time.Millisecond)
+	s.touch("cn=test")
+
+	assert.True(t, s.has("CN=test"))
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, s.has("cn=test"))
+}
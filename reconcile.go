@@ -0,0 +1,236 @@
+package ldapx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// reconcilePageSize is the page size of the search for the current entries.
+const reconcilePageSize = 500
+
+// ReconcileOption configures Reconcile.
+type ReconcileOption func(*reconcileOptions)
+
+// reconcileOptions holds the settings of Reconcile.
+type reconcileOptions struct {
+	ownedAttributes []string // Attributes managed on existing entries; those of each desired entry if empty
+	maxDeletes      int      // Most entries that may be deleted, or -1 for no limit
+	dryRun          bool     // Plan the changes without applying them
+}
+
+// WithOwnedAttributes sets the attributes Reconcile manages on existing
+// entries. Owned attributes missing from a desired entry are deleted, and
+// other attributes are left alone, even if the desired entry has them. New
+// entries are added with all the attributes of the desired entry.
+func WithOwnedAttributes(attributes ...string) ReconcileOption {
+	return func(o *reconcileOptions) {
+		o.ownedAttributes = append(o.ownedAttributes, attributes...)
+	}
+}
+
+// WithMaxDeletes makes Reconcile fail with ErrTooManyDeletes, before changing
+// anything, if more than n entries would be deleted.
+func WithMaxDeletes(n int) ReconcileOption {
+	return func(o *reconcileOptions) {
+		o.maxDeletes = n
+	}
+}
+
+// WithDryRun makes Reconcile return the plan without applying it.
+func WithDryRun() ReconcileOption {
+	return func(o *reconcileOptions) {
+		o.dryRun = true
+	}
+}
+
+// ReconcilePlan holds the changes that make a subtree match the desired
+// entries, in the order they are applied.
+type ReconcilePlan struct {
+	Adds     []*Entry // Entries to add, parents first
+	Modifies []*Entry // Existing entries with the changes to apply
+	Deletes  []*Entry // Existing entries to delete, children first
+}
+
+// Len returns the number of entries the plan changes.
+func (p *ReconcilePlan) Len() int {
+	return len(p.Adds) + len(p.Modifies) + len(p.Deletes)
+}
+
+// Entries returns the entries of the plan in the order they are applied:
+// adds, then modifies, then deletes.
+func (p *ReconcilePlan) Entries() []*Entry {
+	entries := make([]*Entry, 0, p.Len())
+	entries = append(entries, p.Adds...)
+	entries = append(entries, p.Modifies...)
+	return append(entries, p.Deletes...)
+}
+
+// WriteLDIF writes the plan to w as LDIF change records, in the order they
+// are applied.
+func (p *ReconcilePlan) WriteLDIF(w io.Writer) error {
+	lw := NewLDIFWriter(w)
+	for _, e := range p.Entries() {
+		if err := lw.WriteChange(e); err != nil {
+			return err
+		}
+	}
+	return lw.Flush()
+}
+
+// Reconcile makes the entries below baseDN that match filter the same as the
+// desired entries: desired entries that do not exist are added, existing
+// entries have their attributes changed to the desired values, and entries
+// that are not desired are deleted. The base entry itself is not managed, and
+// desired entries must be below it. The filter should match every entry the
+// desired entries may name, or adding those it misses will fail.
+//
+// It returns the plan, also when applying it fails. Changes are applied in
+// the order of the plan and stop at the first that fails.
+func (c *Conn) Reconcile(baseDN, filter string, desired []*Entry, opts ...ReconcileOption) (*ReconcilePlan, error) {
+	return c.ReconcileContext(context.Background(), baseDN, filter, desired, opts...)
+}
+
+// ReconcileContext makes the entries below baseDN that match filter the same
+// as the desired entries. See Reconcile.
+func (c *Conn) ReconcileContext(ctx context.Context, baseDN, filter string, desired []*Entry, opts ...ReconcileOption) (*ReconcilePlan, error) {
+	o := &reconcileOptions{maxDeletes: -1}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	plan, err := c.planReconcile(ctx, baseDN, filter, desired, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.maxDeletes >= 0 && len(plan.Deletes) > o.maxDeletes {
+		return plan, &OpError{Op: "reconcile", DN: baseDN, Err: fmt.Errorf("%w: %d entries, limit %d", ErrTooManyDeletes, len(plan.Deletes), o.maxDeletes)}
+	}
+	if o.dryRun {
+		return plan, nil
+	}
+	return plan, c.applyReconcilePlan(ctx, plan)
+}
+
+// planReconcile compares the desired entries with those on the server.
+func (c *Conn) planReconcile(ctx context.Context, baseDN, filter string, desired []*Entry, o *reconcileOptions) (*ReconcilePlan, error) {
+	base, err := ParseDN(baseDN)
+	if err != nil {
+		return nil, &OpError{Op: "reconcile", DN: baseDN, Err: err}
+	}
+
+	// Key the desired entries by their normalized DN.
+	want := make(map[string]*Entry, len(desired))
+	for _, e := range desired {
+		dn, err := ParseDN(e.DN)
+		if err != nil {
+			return nil, &OpError{Op: "reconcile", DN: e.DN, Err: err}
+		}
+		if !dn.IsDescendantOf(base) {
+			return nil, &OpError{Op: "reconcile", DN: e.DN, Err: fmt.Errorf("entry is not below %s", baseDN)}
+		}
+		key := dn.Normalize(nil).String()
+		if _, ok := want[key]; ok {
+			return nil, &OpError{Op: "reconcile", DN: e.DN, Err: errors.New("entry is desired more than once")}
+		}
+		want[key] = e
+	}
+
+	attributes := []string{"*"}
+	if len(o.ownedAttributes) > 0 {
+		attributes = o.ownedAttributes
+	}
+	request := NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, filter, attributes, nil)
+
+	plan := &ReconcilePlan{}
+	seen := make(map[string]bool, len(desired))
+	for current, err := range c.SearchIterContext(ctx, request, reconcilePageSize) {
+		if err != nil {
+			return nil, err
+		}
+		dn, err := ParseDN(current.DN)
+		if err != nil {
+			return nil, &OpError{Op: "reconcile", DN: current.DN, Err: err}
+		}
+		if !dn.IsDescendantOf(base) {
+			continue
+		}
+		key := dn.Normalize(nil).String()
+		d, ok := want[key]
+		if !ok {
+			current.ChangeType = ChangeDelete
+			plan.Deletes = append(plan.Deletes, current)
+			continue
+		}
+		seen[key] = true
+		c.bindEntry(current)
+		reconcileAttributes(current, d, o.ownedAttributes)
+		if current.Changed() {
+			plan.Modifies = append(plan.Modifies, current)
+		}
+	}
+
+	for key, d := range want {
+		if !seen[key] {
+			plan.Adds = append(plan.Adds, d.Clone())
+		}
+	}
+
+	// Parents are added before their children and deleted after them.
+	sortByDepth(plan.Adds, true)
+	sortByDepth(plan.Modifies, true)
+	sortByDepth(plan.Deletes, false)
+
+	return plan, nil
+}
+
+// sortByDepth sorts the entries by the depth of their DN, parents first or
+// children first, and then by DN.
+func sortByDepth(entries []*Entry, parentsFirst bool) {
+	depth := make(map[*Entry]int, len(entries))
+	for _, e := range entries {
+		if dn, err := ParseDN(e.DN); err == nil {
+			depth[e] = dn.Depth()
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if di, dj := depth[entries[i]], depth[entries[j]]; di != dj {
+			return di < dj == parentsFirst
+		}
+		return strings.ToLower(entries[i].DN) < strings.ToLower(entries[j].DN)
+	})
+}
+
+// reconcileAttributes changes the owned attributes of the current entry to
+// the values of the desired entry. Without owned attributes, those of the
+// desired entry are changed.
+func reconcileAttributes(current, desired *Entry, owned []string) {
+	if len(owned) == 0 {
+		owned = desired.AttributeNames()
+	}
+	current.ApplyDesired(desired, onlyAttributes(owned...))
+}
+
+// applyReconcilePlan applies the changes of the plan in order.
+func (c *Conn) applyReconcilePlan(ctx context.Context, plan *ReconcilePlan) error {
+	for _, e := range plan.Entries() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if e.ChangeType == ChangeDelete {
+			err = c.DelContext(ctx, buildDelRequest(e.DN))
+		} else {
+			err = e.UpdateContext(ctx, c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
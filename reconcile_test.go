@@ -0,0 +1,187 @@
+package ldapx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReconcileServer returns a server holding a small subtree below
+// ou=people,dc=example,dc=com.
+func newReconcileServer(t *testing.T) *fakeServer {
+	s := newFakeServer(t)
+	s.put("dc=example,dc=com", map[string][]string{"objectClass": {"domain"}, "dc": {"example"}})
+	s.put("ou=people,dc=example,dc=com", map[string][]string{"objectClass": {"organizationalUnit"}, "ou": {"people"}})
+	s.put("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"alice"}, "cn": {"Alice"}, "mail": {"alice@example.com"}, "description": {"local"},
+	})
+	s.put("uid=bob,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"bob"}, "cn": {"Bob"},
+	})
+	s.put("ou=old,ou=people,dc=example,dc=com", map[string][]string{"objectClass": {"organizationalUnit"}, "ou": {"old"}})
+	s.put("uid=carol,ou=old,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"carol"}, "cn": {"Carol"},
+	})
+	return s
+}
+
+// desiredPeople returns the desired entries of the reconcile tests.
+func desiredPeople() []*Entry {
+	alice := NewEntry("uid=alice,ou=people,dc=example,dc=com")
+	alice.AddAttributeValues("objectClass", []string{"inetOrgPerson"})
+	alice.AddAttributeValue("uid", "alice")
+	alice.AddAttributeValue("cn", "Alice Smith")
+
+	staff := NewEntry("ou=staff,ou=people,dc=example,dc=com")
+	staff.AddAttributeValues("objectClass", []string{"organizationalUnit"})
+	staff.AddAttributeValue("ou", "staff")
+
+	dave := NewEntry("uid=dave,ou=staff,ou=people,dc=example,dc=com")
+	dave.AddAttributeValues("objectClass", []string{"inetOrgPerson"})
+	dave.AddAttributeValue("uid", "dave")
+	dave.AddAttributeValue("cn", "Dave")
+
+	// The child is given before its parent.
+	return []*Entry{dave, alice, staff}
+}
+
+func entryDNs(entries []*Entry) []string {
+	dns := make([]string, 0, len(entries))
+	for _, e := range entries {
+		dns = append(dns, strings.ToLower(e.DN))
+	}
+	return dns
+}
+
+func TestConn_Reconcile(t *testing.T) {
+	s := newReconcileServer(t)
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	plan, err := conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", desiredPeople())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ou=staff,ou=people,dc=example,dc=com", "uid=dave,ou=staff,ou=people,dc=example,dc=com"}, entryDNs(plan.Adds))
+	assert.Equal(t, []string{"uid=alice,ou=people,dc=example,dc=com"}, entryDNs(plan.Modifies))
+	assert.Equal(t, []string{
+		"uid=carol,ou=old,ou=people,dc=example,dc=com",
+		"ou=old,ou=people,dc=example,dc=com",
+		"uid=bob,ou=people,dc=example,dc=com",
+	}, entryDNs(plan.Deletes))
+	assert.Equal(t, 6, plan.Len())
+
+	// The base entry is not managed.
+	assert.NotNil(t, s.get("ou=people,dc=example,dc=com"))
+	assert.NotNil(t, s.get("uid=dave,ou=staff,ou=people,dc=example,dc=com"))
+	assert.Nil(t, s.get("uid=bob,ou=people,dc=example,dc=com"))
+	assert.Nil(t, s.get("ou=old,ou=people,dc=example,dc=com"))
+
+	// Attributes the desired entry does not name are left alone.
+	alice := s.get("uid=alice,ou=people,dc=example,dc=com")
+	assert.Equal(t, []string{"Alice Smith"}, alice["cn"])
+	assert.Equal(t, []string{"alice@example.com"}, alice["mail"])
+
+	// Reconciling again changes nothing.
+	plan, err = conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", desiredPeople())
+	require.NoError(t, err)
+	assert.Equal(t, 0, plan.Len())
+}
+
+func TestConn_ReconcileOwnedAttributes(t *testing.T) {
+	s := newReconcileServer(t)
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	desired := desiredPeople()
+	desired[1].AddAttributeValue("description", "managed")
+	plan, err := conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=inetOrgPerson)", desired, WithOwnedAttributes("cn", "mail"))
+	require.NoError(t, err)
+
+	// Only entries matching the filter are deleted.
+	assert.Equal(t, []string{"uid=carol,ou=old,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"}, entryDNs(plan.Deletes))
+	assert.NotNil(t, s.get("ou=old,ou=people,dc=example,dc=com"))
+
+	// Owned attributes are changed, including mail, which is not desired;
+	// description is not owned and is left alone.
+	alice := s.get("uid=alice,ou=people,dc=example,dc=com")
+	assert.Equal(t, []string{"Alice Smith"}, alice["cn"])
+	assert.Nil(t, alice["mail"])
+	assert.Equal(t, []string{"local"}, alice["description"])
+
+	// New entries get all of the desired attributes.
+	assert.Equal(t, []string{"dave"}, s.get("uid=dave,ou=staff,ou=people,dc=example,dc=com")["uid"])
+}
+
+func TestConn_ReconcileDryRun(t *testing.T) {
+	s := newReconcileServer(t)
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	plan, err := conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", desiredPeople(), WithDryRun(), WithOwnedAttributes("cn"))
+	require.NoError(t, err)
+	assert.Equal(t, 6, plan.Len())
+
+	// Nothing is changed.
+	assert.Nil(t, s.get("ou=staff,ou=people,dc=example,dc=com"))
+	assert.NotNil(t, s.get("uid=bob,ou=people,dc=example,dc=com"))
+	assert.Equal(t, []string{"Alice"}, s.get("uid=alice,ou=people,dc=example,dc=com")["cn"])
+
+	var b strings.Builder
+	require.NoError(t, plan.WriteLDIF(&b))
+	ldif := b.String()
+	assert.True(t, strings.HasPrefix(ldif, "version: 1\n\ndn: ou=staff,ou=people,dc=example,dc=com\nchangetype: add\n"), ldif)
+	assert.Contains(t, ldif, "dn: uid=alice,ou=people,dc=example,dc=com\nchangetype: modify\n")
+	assert.Contains(t, ldif, "delete: cn\n-\nadd: cn\ncn: Alice Smith\n-\n")
+	assert.True(t, strings.HasSuffix(ldif, "dn: uid=bob,ou=people,dc=example,dc=com\nchangetype: delete\n"), ldif)
+	assert.Less(t, strings.Index(ldif, "dn: uid=carol"), strings.Index(ldif, "dn: ou=old"))
+}
+
+func TestConn_ReconcileMaxDeletes(t *testing.T) {
+	s := newReconcileServer(t)
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	plan, err := conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", desiredPeople(), WithMaxDeletes(2))
+	assert.True(t, errors.Is(err, ErrTooManyDeletes), err)
+	require.NotNil(t, plan)
+	assert.Len(t, plan.Deletes, 3)
+
+	// Nothing is changed.
+	assert.Nil(t, s.get("ou=staff,ou=people,dc=example,dc=com"))
+	assert.NotNil(t, s.get("uid=bob,ou=people,dc=example,dc=com"))
+
+	_, err = conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", desiredPeople(), WithMaxDeletes(3))
+	assert.NoError(t, err)
+}
+
+func TestConn_ReconcileInvalid(t *testing.T) {
+	s := newReconcileServer(t)
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	outside := NewEntry("uid=eve,dc=example,dc=com")
+	_, err = conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", []*Entry{outside})
+	assert.Error(t, err)
+
+	base := NewEntry("ou=People,dc=example,dc=com")
+	_, err = conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", []*Entry{base})
+	assert.Error(t, err)
+
+	twice := []*Entry{NewEntry("uid=x,ou=people,dc=example,dc=com"), NewEntry("UID=X,ou=people,dc=example,dc=com")}
+	_, err = conn.Reconcile("ou=people,dc=example,dc=com", "(objectClass=*)", twice)
+	assert.Error(t, err)
+
+	_, err = conn.Reconcile("ou=missing,dc=example,dc=com", "(objectClass=*)", nil)
+	assert.True(t, IsNotFound(err), err)
+
+	// Nothing is changed.
+	assert.NotNil(t, s.get("uid=bob,ou=people,dc=example,dc=com"))
+}
@@ -2,13 +2,18 @@ package ldapx
 
 import (
 	"github.com/go-ldap/ldap/v3"
-	"strings"
 )
 
 // ReplaceAttributeValuesIgnoreCase	removes all values from the attribute and adds the given values, ignoring case if ignoreCase is true.
 func (e *Entry) ReplaceAttributeValuesIgnoreCase(attr string, value []string, ignoreCase bool) {
+	e.replaceAttributeValues(attr, value, e.valueNormalizer(attr, ignoreCase))
+}
+
+// replaceAttributeValues replaces the values of the attribute unless they
+// are the same as the given values under normalize.
+func (e *Entry) replaceAttributeValues(attr string, value []string, normalize normalizer) {
 	v := e.Attributes.Get(attr)
-	if v != nil && sameStringSlice(value, v.Values, ignoreCase) {
+	if v != nil && sameValues(value, v.Values, normalize) {
 		return
 	}
 	e.Attributes.PutEntryAttribute(ldap.NewEntryAttribute(attr, value))
@@ -29,24 +34,19 @@ func (e *Entry) ReplaceAttributeValue(attr string, value string) {
 	e.ReplaceAttributeValueIgnoreCase(attr, value, false)
 }
 
-// sameStringSlice compares two string slices and returns true if they are the same, ignoring case if ignoreCase is true.
-func sameStringSlice(x, y []string, ignoreCase bool) bool {
+// sameValues returns true if the two slices hold the same values once normalized, in any order.
+func sameValues(x, y []string, normalize normalizer) bool {
 	if len(x) != len(y) {
 		return false
 	}
 	// create a map of string -> int
 	diff := make(map[string]int, len(x))
 	for _, _x := range x {
-		if ignoreCase {
-			_x = strings.ToLower(_x)
-		}
 		// 0 value for int is 0, so just increment a counter for the string
-		diff[_x]++
+		diff[normalize(_x)]++
 	}
 	for _, _y := range y {
-		if ignoreCase {
-			_y = strings.ToLower(_y)
-		}
+		_y = normalize(_y)
 		// If the string _y is not in diff bail out early
 		if _, ok := diff[_y]; !ok {
 			return false
@@ -0,0 +1,151 @@
+package ldapx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// RetryPolicy controls how operations that fail with a transient error are
+// retried. Searches, compares, deletes and modifies that only replace or
+// delete values are retried; adds, modifies that add values and password
+// modifies are only retried if RetryWrites is set. A delete that is retried
+// after the connection broke and then finds nothing to delete is taken to
+// have been applied by the earlier attempt, whose reply was lost.
+type RetryPolicy struct {
+	MaxAttempts    int              // Total number of attempts; 1 or less disables retries
+	InitialBackoff time.Duration    // Delay before the first retry
+	MaxBackoff     time.Duration    // Upper bound on the delay between retries
+	Multiplier     float64          // Factor the delay grows by after each retry
+	Jitter         float64          // Fraction of the delay that is randomised, between 0 and 1
+	RetryWrites    bool             // Retry non-idempotent writes as well
+	Retryable      func(error) bool // Classifies errors; IsRetryable if nil
+}
+
+// DefaultRetryPolicy returns a policy that makes up to three attempts,
+// starting with a 100ms backoff.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetry sets the retry policy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *config) {
+		c.retry = policy
+	}
+}
+
+// retryableCodes are the result codes of errors that may succeed when retried.
+var retryableCodes = []uint16{
+	ldap.ErrorNetwork,
+	ldap.LDAPResultBusy,
+	ldap.LDAPResultUnavailable,
+	ldap.LDAPResultServerDown,
+	ldap.LDAPResultConnectError,
+}
+
+// IsRetryable returns true if the error is transient: a network failure, or
+// a busy or unavailable server.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return ldap.IsErrorAnyOf(err, retryableCodes...)
+}
+
+// isConnError returns true if the error means the connection is broken.
+func isConnError(err error) bool {
+	return ldap.IsErrorAnyOf(err, ldap.ErrorNetwork, ldap.LDAPResultServerDown, ldap.LDAPResultConnectError)
+}
+
+// isIdempotentModify returns true if the modify request can safely be sent twice.
+func isIdempotentModify(request *ldap.ModifyRequest) bool {
+	for _, change := range request.Changes {
+		if change.Operation == ldap.AddAttribute || change.Operation == ldap.IncrementAttribute {
+			return false
+		}
+	}
+	return true
+}
+
+// appliedOnRetry wraps the write f so that, once an attempt was cut off by a
+// broken connection, a later attempt failing with one of the given result
+// codes counts as success: the earlier attempt was applied and only its
+// reply was lost.
+func appliedOnRetry(f func(*ldap.Conn) (interface{}, error), codes ...uint16) func(*ldap.Conn) (interface{}, error) {
+	var lost bool
+	return func(conn *ldap.Conn) (interface{}, error) {
+		result, err := f(conn)
+		if lost && ldap.IsErrorAnyOf(err, codes...) {
+			return result, nil
+		}
+		lost = lost || isConnError(connError(conn, err, false))
+		return result, err
+	}
+}
+
+// hasDelete returns true if the modify request deletes an attribute or values.
+func hasDelete(request *ldap.ModifyRequest) bool {
+	for _, change := range request.Changes {
+		if change.Operation == ldap.DeleteAttribute {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry calls op until it succeeds, fails with a permanent error or the
+// attempts are used up. Non-idempotent operations are only retried if the
+// policy allows it.
+func (c *Conn) withRetry(ctx context.Context, idempotent bool, op func() (interface{}, error)) (interface{}, error) {
+	// A transaction is bound to one connection, so there is nothing to retry on.
+	policy := c.retry
+	if c.txConn != nil {
+		policy.MaxAttempts = 1
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := op()
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) || (!idempotent && !policy.RetryWrites) {
+			return result, err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the given retry.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if jitter := math.Min(math.Max(p.Jitter, 0), 1); jitter > 0 {
+		d -= d * jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
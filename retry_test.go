@@ -0,0 +1,168 @@
+package ldapx
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetry is a retry policy with short backoffs for tests.
+var fastRetry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2, Jitter: 0.5}
+
+// failFirst returns an override that fails the first n requests of the given
+// operation with the result code.
+func failFirst(n int32, op ber.Tag, code uint16) func(ber.Tag, string) uint16 {
+	var count atomic.Int32
+	return func(o ber.Tag, _ string) uint16 {
+		if o == op && count.Add(1) <= n {
+			return code
+		}
+		return ldap.LDAPResultSuccess
+	}
+}
+
+func TestConn_RetryBusySearch(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithRetry(fastRetry), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s.setOverride(failFirst(2, ldap.ApplicationSearchRequest, ldap.LDAPResultBusy))
+	before := s.searches.Load()
+
+	_, err = conn.Lookup("")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, s.searches.Load()-before)
+}
+
+func TestConn_RetryGivesUp(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithRetry(fastRetry), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s.setOverride(failFirst(10, ldap.ApplicationSearchRequest, ldap.LDAPResultUnavailable))
+	before := s.searches.Load()
+
+	_, err = conn.Lookup("")
+	assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailable))
+	assert.EqualValues(t, 3, s.searches.Load()-before)
+}
+
+func TestConn_RetryPermanentError(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithRetry(fastRetry), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	before := s.searches.Load()
+
+	_, err = conn.Lookup("cn=missing")
+	assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject))
+	assert.EqualValues(t, 1, s.searches.Load()-before)
+}
+
+func TestConn_RetryBrokenConnection(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithRetry(fastRetry), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s.dropConns()
+
+	_, err = conn.Lookup("")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, s.dials.Load())
+}
+
+func TestConn_RetryWrites(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithRetry(fastRetry), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Adds are not retried by default.
+	s.setOverride(failFirst(1, ldap.ApplicationAddRequest, ldap.LDAPResultBusy))
+	err = conn.Add(NewAddRequest("cn=test", nil))
+	assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultBusy), "%v", err)
+
+	// Modifies that only replace values are.
+	s.setOverride(failFirst(1, ldap.ApplicationModifyRequest, ldap.LDAPResultBusy))
+	modify := NewModifyRequest("cn=test", nil)
+	modify.Replace("cn", []string{"test"})
+	require.NoError(t, conn.Modify(modify))
+
+	// Modifies that add values are not.
+	s.setOverride(failFirst(1, ldap.ApplicationModifyRequest, ldap.LDAPResultBusy))
+	modify = NewModifyRequest("cn=test", nil)
+	modify.Add("cn", []string{"test"})
+	assert.Error(t, conn.Modify(modify))
+
+	// Unless the caller opts in.
+	policy := fastRetry
+	policy.RetryWrites = true
+	conn.retry = policy
+	s.setOverride(failFirst(1, ldap.ApplicationAddRequest, ldap.LDAPResultBusy))
+	require.NoError(t, conn.Add(NewAddRequest("cn=test", nil)))
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(ldap.NewError(ldap.ErrorNetwork, errors.New("closed"))))
+	assert.True(t, IsRetryable(ldap.NewError(ldap.LDAPResultBusy, errors.New("busy"))))
+	assert.False(t, IsRetryable(ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("missing"))))
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2}
+
+	assert.Equal(t, 100*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 300*time.Millisecond, p.backoff(3))
+
+	p.Jitter = 0.5
+	for range 10 {
+		d := p.backoff(1)
+		assert.True(t, d > 50*time.Millisecond && d <= 100*time.Millisecond)
+	}
+}
+
+func TestConn_RetryLostDeleteReply(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=test", map[string][]string{"cn": {"test"}, "mail": {"a@example.com", "b@example.com"}})
+
+	conn, err := Open(s.url(), WithRetry(fastRetry), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The value is deleted but the reply is lost; the retry finds it gone.
+	s.lostAcks.Store(1)
+	modify := NewModifyRequest("cn=test", nil)
+	modify.Delete("mail", []string{"a@example.com"})
+	require.NoError(t, conn.Modify(modify))
+	assert.Equal(t, []string{"b@example.com"}, s.get("cn=test")["mail"])
+
+	// Without a lost reply, deleting a value that does not exist still fails.
+	err = conn.Modify(modify)
+	assert.True(t, errors.Is(err, ErrNoSuchAttribute), "%v", err)
+
+	// Likewise for the entry.
+	s.lostAcks.Store(1)
+	require.NoError(t, conn.Del(NewDelRequest("cn=test", nil)))
+	assert.Nil(t, s.get("cn=test"))
+	assert.EqualValues(t, 3, s.dials.Load())
+
+	err = conn.Del(NewDelRequest("cn=test", nil))
+	assert.True(t, IsNotFound(err), "%v", err)
+}
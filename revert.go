@@ -0,0 +1,116 @@
+package ldapx
+
+import (
+	"context"
+	"math/big"
+	"strings"
+)
+
+// EntrySnapshot is the state of an entry saved by Snapshot.
+type EntrySnapshot struct {
+	dn         string            // DN of the entry
+	changeType string            // Change type of the entry
+	attributes AttributeMap      // Copy of the attributes
+	changes    []AttributeChange // Copy of the pending changes
+}
+
+// copyAttributeMap returns a copy of the map and of its attributes.
+func copyAttributeMap(m AttributeMap) AttributeMap {
+	c := make(AttributeMap, len(m))
+	for k, a := range m {
+		c[k] = copyEntryAttribute(a)
+	}
+	return c
+}
+
+// Snapshot saves the attributes and pending changes of the entry, so that
+// Restore can return to them. Snapshots may be nested: restoring one undoes
+// the changes made since it was taken, including later snapshots.
+func (e *Entry) Snapshot() *EntrySnapshot {
+	return &EntrySnapshot{
+		dn:         e.DN,
+		changeType: e.ChangeType,
+		attributes: copyAttributeMap(e.Attributes),
+		changes:    append([]AttributeChange(nil), e.Changes...),
+	}
+}
+
+// Restore returns the entry to the state saved by Snapshot. The snapshot can
+// be restored more than once.
+func (e *Entry) Restore(s *EntrySnapshot) {
+	e.DN = s.dn
+	e.ChangeType = s.changeType
+	e.Attributes = copyAttributeMap(s.attributes)
+	e.Changes = append([]AttributeChange(nil), s.changes...)
+}
+
+// Revert discards the pending changes and restores the attributes the entry
+// had when it was created or looked up. The server is not changed.
+func (e *Entry) Revert() {
+	e.Attributes = copyAttributeMap(e.originalAttributes)
+	e.Changes = nil
+}
+
+// InverseChanges returns the changes that undo those made to the entry since
+// it was created or looked up, once they have been applied: the changes that
+// make the attributes the same as the original attributes. Increments are
+// undone by incrementing by the negated value.
+func (e *Entry) InverseChanges() []AttributeChange {
+	original := &Entry{Attributes: e.originalAttributes, schema: e.schema}
+	changes := Diff(e, original)
+
+	for _, c := range e.Changes {
+		if c.Action != "increment" {
+			continue
+		}
+		values := make([]string, 0, len(c.Value))
+		for _, v := range c.Value {
+			n, ok := new(big.Int).SetString(strings.TrimSpace(v), 10)
+			if !ok {
+				continue
+			}
+			values = append(values, n.Neg(n).String())
+		}
+		changes = append(changes, AttributeChange{Action: "increment", Attr: c.Attr, Value: values})
+	}
+	return changes
+}
+
+// Undo undoes the changes applied to the server by Update: an added entry is
+// deleted, a deleted entry is added back with its original attributes, and an
+// updated entry is modified by InverseChanges. The entry is then reverted, and
+// can be updated again.
+func (e *Entry) Undo(conn *Conn) error {
+	return e.UndoContext(context.Background(), conn)
+}
+
+// UndoContext undoes the changes applied to the server by Update. See Undo.
+func (e *Entry) UndoContext(ctx context.Context, conn *Conn) error {
+	if !e.committed {
+		return &OpError{Op: "undo", DN: e.DN, Err: ErrNotCommitted}
+	}
+
+	var err error
+	switch e.ChangeType {
+	case ChangeAdd:
+		err = conn.DelContext(ctx, buildDelRequest(e.DN))
+	case ChangeDelete:
+		var changes []AttributeChange
+		for _, name := range ldifAttributeNames(&Entry{Attributes: e.originalAttributes}) {
+			a := e.originalAttributes.Get(name)
+			changes = append(changes, AttributeChange{Action: "add", Attr: a.Name, Value: a.Values})
+		}
+		err = conn.AddContext(ctx, buildAddRequest(e.DN, changes))
+	case ChangeUpdate:
+		if changes := e.InverseChanges(); len(changes) > 0 {
+			err = conn.ModifyContext(ctx, buildModifyRequest(e.DN, changes))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	e.Revert()
+	e.committed = false
+	return nil
+}
@@ -0,0 +1,147 @@
+package ldapx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func revertEntry() *Entry {
+	return NewEntryFromLdapEntry(ldap.NewEntry("uid=jdoe,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass": {"top", "person"},
+		"uid":         {"jdoe"},
+		"cn":          {"John Doe"},
+		"mail":        {"jdoe@example.com"},
+		"description": {"old"},
+	}))
+}
+
+func TestEntry_Revert(t *testing.T) {
+	e := revertEntry()
+	e.ReplaceAttributeValue("cn", "Johnny")
+	e.AddAttributeValue("mail", "john@example.com")
+	e.DeleteAttribute("description")
+	e.RenameAttribute("uid", "userid")
+	require.True(t, e.Changed())
+
+	e.Revert()
+	assert.False(t, e.Changed())
+	assert.Equal(t, "John Doe", e.GetAttributeValue("cn"))
+	assert.Equal(t, []string{"jdoe@example.com"}, e.GetAttributeValues("mail"))
+	assert.Equal(t, "old", e.GetAttributeValue("description"))
+	assert.Equal(t, "uid", e.Attributes.Get("uid").Name)
+	assert.False(t, e.AttributeExists("userid"))
+
+	// The entry can be changed again after reverting.
+	e.ReplaceAttributeValue("cn", "Jon")
+	assert.Equal(t, "Jon", e.GetAttributeValue("cn"))
+	e.Revert()
+	assert.Equal(t, "John Doe", e.GetAttributeValue("cn"))
+}
+
+func TestEntry_SnapshotRestore(t *testing.T) {
+	e := revertEntry()
+	e.ReplaceAttributeValue("cn", "Johnny")
+
+	outer := e.Snapshot()
+	e.AddAttributeValue("mail", "john@example.com")
+
+	inner := e.Snapshot()
+	e.DeleteAttribute("description")
+	e.Attributes.Get("mail").Values[0] = "changed in place"
+
+	e.Restore(inner)
+	assert.Equal(t, "old", e.GetAttributeValue("description"))
+	assert.Equal(t, []string{"jdoe@example.com", "john@example.com"}, e.GetAttributeValues("mail"))
+	assert.Len(t, e.Changes, 2)
+
+	e.Restore(outer)
+	assert.Equal(t, []string{"jdoe@example.com"}, e.GetAttributeValues("mail"))
+	assert.Equal(t, []AttributeChange{{Action: "replace", Attr: "cn", Value: []string{"Johnny"}}}, e.Changes)
+
+	// A snapshot can be restored more than once.
+	e.AddAttributeValue("mail", "other@example.com")
+	e.Restore(inner)
+	assert.Equal(t, []string{"jdoe@example.com", "john@example.com"}, e.GetAttributeValues("mail"))
+}
+
+func TestEntry_InverseChanges(t *testing.T) {
+	e := revertEntry()
+	assert.Empty(t, e.InverseChanges())
+
+	e.ReplaceAttributeValue("cn", "Johnny")
+	e.AddAttributeValue("mail", "john@example.com")
+	e.DeleteAttribute("description")
+	e.AddAttributeValue("telephoneNumber", "+1 555 0100")
+	e.AddAttributeChange("increment", "uidNumber", []string{"5"})
+
+	assert.Equal(t, []AttributeChange{
+		{Action: "delete", Attr: "cn"},
+		{Action: "add", Attr: "cn", Value: []string{"John Doe"}},
+		{Action: "add", Attr: "description", Value: []string{"old"}},
+		{Action: "delete", Attr: "mail", Value: []string{"john@example.com"}},
+		{Action: "delete", Attr: "telephoneNumber"},
+		{Action: "increment", Attr: "uidNumber", Value: []string{"-5"}},
+	}, e.InverseChanges())
+}
+
+func TestEntry_Undo(t *testing.T) {
+	s := newFakeServer(t)
+	original := map[string][]string{
+		"objectClass": {"top", "person"},
+		"uid":         {"jdoe"},
+		"cn":          {"John Doe"},
+		"mail":        {"jdoe@example.com"},
+		"description": {"old"},
+	}
+	s.put("uid=jdoe,ou=people,dc=example,dc=com", original)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	e, err := conn.Lookup("uid=jdoe,ou=people,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.True(t, errors.Is(e.Undo(conn), ErrNotCommitted))
+
+	e.ReplaceAttributeValue("cn", "Johnny")
+	e.AddAttributeValue("mail", "john@example.com")
+	e.DeleteAttribute("description")
+	require.NoError(t, e.Update(conn))
+	assert.Equal(t, []string{"Johnny"}, s.get("uid=jdoe,ou=people,dc=example,dc=com")["cn"])
+
+	require.NoError(t, e.Undo(conn))
+	stored := s.get("uid=jdoe,ou=people,dc=example,dc=com")
+	assert.Equal(t, []string{"John Doe"}, stored["cn"])
+	assert.Equal(t, []string{"jdoe@example.com"}, stored["mail"])
+	assert.Equal(t, []string{"old"}, stored["description"])
+
+	// The entry is reverted and can be updated again.
+	assert.False(t, e.Changed())
+	e.ReplaceAttributeValue("cn", "Jon")
+	require.NoError(t, e.Update(conn))
+	assert.Equal(t, []string{"Jon"}, s.get("uid=jdoe,ou=people,dc=example,dc=com")["cn"])
+
+	// An added entry is deleted.
+	added := NewEntry("uid=new,ou=people,dc=example,dc=com")
+	added.AddAttributeValue("uid", "new")
+	require.NoError(t, added.Update(conn))
+	require.NotNil(t, s.get("uid=new,ou=people,dc=example,dc=com"))
+	require.NoError(t, added.Undo(conn))
+	assert.Nil(t, s.get("uid=new,ou=people,dc=example,dc=com"))
+
+	// A deleted entry is added back.
+	deleted, err := conn.Lookup("uid=jdoe,ou=people,dc=example,dc=com")
+	require.NoError(t, err)
+	require.NoError(t, conn.Del(NewDelRequest(deleted.DN, nil)))
+	deleted.ChangeType = ChangeDelete
+	deleted.committed = true
+	require.NoError(t, deleted.Undo(conn))
+	stored = s.get("uid=jdoe,ou=people,dc=example,dc=com")
+	require.NotNil(t, stored)
+	assert.Equal(t, []string{"top", "person"}, stored["objectClass"])
+	assert.Equal(t, []string{"Jon"}, stored["cn"])
+}
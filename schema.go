@@ -1,16 +1,195 @@
 package ldapx
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
 	"github.com/go-ldap/ldap/v3"
 )
 
-// LDAPSchema represents the LDAP schema.
+// LDAPSchema represents the LDAP schema. The definitions are parsed the first
+// time one is looked up.
 type LDAPSchema struct {
 	Syntaxes        []string // Attribute syntaxes
 	MatchingRules   []string // Attribute matching rules
 	MatchingRuleUse []string // Attribute matching rule use
 	AttributeTypes  []string // Attribute types
 	ObjectClasses   []string // Object classes
+
+	defs *schemaDefs // Parsed definitions, built on first use
+}
+
+// schemaDefs holds the parsed definitions of a schema. It is kept behind a
+// pointer so that an LDAPSchema holds no lock and may be copied.
+type schemaDefs struct {
+	attributeTypes   map[string]*AttributeType   // Attribute types keyed by lowercased name and OID
+	objectClasses    map[string]*ObjectClass     // Object classes keyed by lowercased name and OID
+	syntaxes         map[string]*LDAPSyntax      // Syntaxes keyed by OID
+	matchingRules    map[string]*MatchingRule    // Matching rules keyed by lowercased name and OID
+	matchingRuleUses map[string]*MatchingRuleUse // Matching rule uses keyed by lowercased name and OID
+	parseErr         error                       // Errors of definitions that could not be parsed
+}
+
+// schemaMu guards the parsing of schema definitions.
+var schemaMu sync.Mutex
+
+// NewLDAPSchemaFromLdapEntry creates a schema from a subschema subentry.
+func NewLDAPSchemaFromLdapEntry(e *ldap.Entry) *LDAPSchema {
+	return &LDAPSchema{
+		Syntaxes:        e.GetAttributeValues("ldapSyntaxes"),
+		MatchingRules:   e.GetAttributeValues("matchingRules"),
+		MatchingRuleUse: e.GetAttributeValues("matchingRuleUse"),
+		AttributeTypes:  e.GetAttributeValues("attributeTypes"),
+		ObjectClasses:   e.GetAttributeValues("objectClasses"),
+	}
+}
+
+// AttributeType returns the attribute type with the given name or OID, or nil
+// if there is none. Case and attribute options, such as ";binary", are ignored.
+func (s *LDAPSchema) AttributeType(nameOrOID string) *AttributeType {
+	name, _, _ := strings.Cut(nameOrOID, ";")
+	return s.definitions().attributeTypes[strings.ToLower(name)]
+}
+
+// ObjectClass returns the object class with the given name or OID, or nil if
+// there is none. Case is ignored.
+func (s *LDAPSchema) ObjectClass(nameOrOID string) *ObjectClass {
+	return s.definitions().objectClasses[strings.ToLower(nameOrOID)]
+}
+
+// LDAPSyntax returns the syntax with the given OID, or nil if there is none.
+func (s *LDAPSchema) LDAPSyntax(oid string) *LDAPSyntax {
+	return s.definitions().syntaxes[strings.ToLower(oid)]
+}
+
+// MatchingRule returns the matching rule with the given name or OID, or nil
+// if there is none. Case is ignored.
+func (s *LDAPSchema) MatchingRule(nameOrOID string) *MatchingRule {
+	return s.definitions().matchingRules[strings.ToLower(nameOrOID)]
+}
+
+// MatchingRuleUseFor returns the matching rule use of the matching rule with
+// the given name or OID, or nil if there is none. Case is ignored.
+func (s *LDAPSchema) MatchingRuleUseFor(nameOrOID string) *MatchingRuleUse {
+	return s.definitions().matchingRuleUses[strings.ToLower(nameOrOID)]
+}
+
+// ParseError returns the errors of the definitions that could not be parsed,
+// or nil. Definitions that cannot be parsed are left out of the lookups.
+func (s *LDAPSchema) ParseError() error {
+	return s.definitions().parseErr
+}
+
+// definitions returns the parsed definitions, parsing them the first time.
+func (s *LDAPSchema) definitions() *schemaDefs {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	if s.defs == nil {
+		s.defs = parseSchema(s)
+	}
+	return s.defs
+}
+
+// parseSchema parses the definitions of the schema and builds the lookup
+// indexes.
+func parseSchema(s *LDAPSchema) *schemaDefs {
+	d := &schemaDefs{}
+	var errs []error
+
+	d.attributeTypes = make(map[string]*AttributeType)
+	for _, def := range s.AttributeTypes {
+		at, err := ParseAttributeType(def)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		indexDefinition(d.attributeTypes, at, at.OID, at.Names)
+	}
+	for _, at := range d.attributeTypes {
+		d.inherit(at, 0)
+	}
+
+	d.objectClasses = make(map[string]*ObjectClass)
+	for _, def := range s.ObjectClasses {
+		oc, err := ParseObjectClass(def)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		indexDefinition(d.objectClasses, oc, oc.OID, oc.Names)
+	}
+
+	d.syntaxes = make(map[string]*LDAPSyntax)
+	for _, def := range s.Syntaxes {
+		syntax, err := ParseLDAPSyntax(def)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		indexDefinition(d.syntaxes, syntax, syntax.OID, nil)
+	}
+
+	d.matchingRules = make(map[string]*MatchingRule)
+	for _, def := range s.MatchingRules {
+		mr, err := ParseMatchingRule(def)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		indexDefinition(d.matchingRules, mr, mr.OID, mr.Names)
+	}
+
+	d.matchingRuleUses = make(map[string]*MatchingRuleUse)
+	for _, def := range s.MatchingRuleUse {
+		mru, err := ParseMatchingRuleUse(def)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		indexDefinition(d.matchingRuleUses, mru, mru.OID, mru.Names)
+	}
+
+	d.parseErr = errors.Join(errs...)
+	return d
+}
+
+// maxSupDepth bounds the superior chains followed, in case of a cycle.
+const maxSupDepth = 32
+
+// inherit fills in the matching rules and syntax of an attribute type from
+// its superior types.
+func (d *schemaDefs) inherit(at *AttributeType, depth int) {
+	if at.Sup == "" || depth >= maxSupDepth {
+		return
+	}
+	sup := d.attributeTypes[strings.ToLower(at.Sup)]
+	if sup == nil || sup == at {
+		return
+	}
+	d.inherit(sup, depth+1)
+
+	if at.Equality == "" {
+		at.Equality = sup.Equality
+	}
+	if at.Ordering == "" {
+		at.Ordering = sup.Ordering
+	}
+	if at.Substr == "" {
+		at.Substr = sup.Substr
+	}
+	if at.Syntax == "" {
+		at.Syntax, at.SyntaxLength = sup.Syntax, sup.SyntaxLength
+	}
+}
+
+// indexDefinition adds a definition to an index under its OID and names.
+func indexDefinition[T any](index map[string]*T, def *T, oid string, names []string) {
+	index[strings.ToLower(oid)] = def
+	for _, name := range names {
+		index[strings.ToLower(name)] = def
+	}
 }
 
 // RootDSE represents the RootDSE.
@@ -29,23 +208,23 @@ type RootDSE struct {
 	VendorVersion                string   // Vendor version
 }
 
-// AttributeType represents an attribute type.
-type AttributeType struct {
-	OID         string
-	Name        string
-	Syntax      string
-	SingleValue bool
-}
-
 // Schema returns the LDAP schema.
 func (c *Conn) Schema() (*LDAPSchema, error) {
-	rootDSE, err := c.RootDSE()
+	return c.SchemaContext(context.Background())
+}
+
+// SchemaContext returns the LDAP schema.
+func (c *Conn) SchemaContext(ctx context.Context) (*LDAPSchema, error) {
+	rootDSE, err := c.RootDSEContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if rootDSE == nil {
+		return nil, &OpError{Op: "search", Err: ErrNotFound}
+	}
 
 	//
-	result, err := c.Search(NewSearchRequest(
+	result, err := c.SearchContext(ctx, NewSearchRequest(
 		rootDSE.SubschemaSubEntry,
 		ldap.ScopeBaseObject, ldap.NeverDerefAliases,
 		0, 0, false,
@@ -64,15 +243,9 @@ func (c *Conn) Schema() (*LDAPSchema, error) {
 	}
 
 	for _, e := range result.Entries {
-		return &LDAPSchema{
-			Syntaxes:        e.GetAttributeValues("ldapSyntaxes"),
-			MatchingRules:   e.GetAttributeValues("matchingRules"),
-			MatchingRuleUse: e.GetAttributeValues("matchingRuleUse"),
-			AttributeTypes:  e.GetAttributeValues("attributeTypes"),
-			ObjectClasses:   e.GetAttributeValues("objectClasses"),
-		}, nil
+		return NewLDAPSchemaFromLdapEntry(e), nil
 	}
-	return nil, err
+	return nil, &OpError{Op: "search", DN: rootDSE.SubschemaSubEntry, Err: ErrNotFound}
 }
 
 // rootDSE returns the RootDSE.
@@ -122,10 +295,16 @@ func rootDSE(conn *ldap.Conn) (*RootDSE, error) {
 
 // RootDSE returns the RootDSE.
 func (c *Conn) RootDSE() (*RootDSE, error) {
-	conn, err := getConn(c.pool)
+	return c.RootDSEContext(context.Background())
+}
+
+// RootDSEContext returns the RootDSE.
+func (c *Conn) RootDSEContext(ctx context.Context) (*RootDSE, error) {
+	result, err := c.executeRead(ctx, "search", "", func(conn *ldap.Conn) (interface{}, error) {
+		return rootDSE(conn)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer putConn(c.pool, conn)
-	return rootDSE(conn)
+	return result.(*RootDSE), nil
 }
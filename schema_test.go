@@ -0,0 +1,204 @@
+package ldapx
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadSchemaDump reads a subschema subentry fixture. The fixtures are
+// hand-written LDIF content records without base64 values, modelled on what
+// each server returns.
+func loadSchemaDump(t *testing.T, name string) *LDAPSchema {
+	t.Helper()
+
+	f, err := os.Open("testdata/schema/" + name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, " ") && len(lines) > 0:
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	entry := &ldap.Entry{}
+	values := make(map[string][]string)
+	var names []string
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ": ")
+		require.True(t, ok, line)
+		if name == "dn" {
+			entry.DN = value
+			continue
+		}
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], value)
+	}
+	for _, name := range names {
+		entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(name, values[name]))
+	}
+	return NewLDAPSchemaFromLdapEntry(entry)
+}
+
+func TestLDAPSchema_OpenLDAP(t *testing.T) {
+	s := loadSchemaDump(t, "openldap.ldif")
+	require.NoError(t, s.ParseError())
+
+	cn := s.AttributeType("commonName")
+	require.NotNil(t, cn)
+	assert.Equal(t, "2.5.4.3", cn.OID)
+	assert.Equal(t, "cn", cn.Name)
+	assert.Equal(t, []string{"cn", "commonName"}, cn.Names)
+	assert.Equal(t, "name", cn.Sup)
+	assert.Equal(t, "caseIgnoreMatch", cn.Equality)
+	assert.Equal(t, "caseIgnoreSubstringsMatch", cn.Substr)
+	assert.Equal(t, "1.3.6.1.4.1.1466.115.121.1.15", cn.Syntax)
+	assert.Equal(t, 32768, cn.SyntaxLength)
+	assert.Same(t, cn, s.AttributeType("CN"))
+	assert.Same(t, cn, s.AttributeType("2.5.4.3"))
+
+	assert.Equal(t, "distinguishedNameMatch", s.AttributeType("member").Equality)
+	assert.Same(t, s.AttributeType("userCertificate"), s.AttributeType("userCertificate;binary"))
+
+	created := s.AttributeType("createTimestamp")
+	require.NotNil(t, created)
+	assert.True(t, created.SingleValue)
+	assert.True(t, created.NoUserModification)
+	assert.Equal(t, UsageDirectoryOperation, created.Usage)
+	assert.Equal(t, "RFC4512: time which object was created", created.Description)
+	assert.Equal(t, UsageUserApplications, s.AttributeType("uid").Usage)
+
+	person := s.ObjectClass("PERSON")
+	require.NotNil(t, person)
+	assert.Equal(t, KindStructural, person.Kind)
+	assert.Equal(t, []string{"top"}, person.Sup)
+	assert.Equal(t, []string{"sn", "cn"}, person.Must)
+	assert.Equal(t, []string{"userPassword", "telephoneNumber", "seeAlso", "description"}, person.May)
+	assert.Equal(t, KindAbstract, s.ObjectClass("top").Kind)
+	assert.Equal(t, KindAuxiliary, s.ObjectClass("1.3.6.1.1.1.2.0").Kind)
+	assert.Equal(t, "RFC2256: a group of names (DNs)", s.ObjectClass("groupOfNames").Description)
+
+	syntax := s.LDAPSyntax("1.3.6.1.4.1.1466.115.121.1.5")
+	require.NotNil(t, syntax)
+	assert.Equal(t, "Binary", syntax.Description)
+	assert.Equal(t, []string{"TRUE"}, syntax.Extensions["X-NOT-HUMAN-READABLE"])
+
+	rule := s.MatchingRule("caseignorematch")
+	require.NotNil(t, rule)
+	assert.Equal(t, "2.5.13.2", rule.OID)
+	assert.Equal(t, "1.3.6.1.4.1.1466.115.121.1.15", rule.Syntax)
+
+	use := s.MatchingRuleUseFor("integerMatch")
+	require.NotNil(t, use)
+	assert.Equal(t, []string{"uidNumber", "gidNumber"}, use.Applies)
+
+	assert.Nil(t, s.AttributeType("missing"))
+	assert.Nil(t, s.ObjectClass("missing"))
+}
+
+func TestLDAPSchema_389DS(t *testing.T) {
+	s := loadSchemaDump(t, "389ds.ldif")
+	require.NoError(t, s.ParseError())
+
+	uidNumber := s.AttributeType("uidNumber")
+	require.NotNil(t, uidNumber)
+	assert.True(t, uidNumber.SingleValue)
+	assert.Equal(t, []string{"RFC 2307", "user defined"}, uidNumber.Extensions["X-ORIGIN"])
+
+	domain := s.AttributeType("nsAdminDomainName-oid")
+	require.NotNil(t, domain)
+	assert.Equal(t, "nsAdminDomainName", domain.Name)
+
+	unique := s.AttributeType("nsUniqueId")
+	require.NotNil(t, unique)
+	assert.Equal(t, UsageDirectoryOperation, unique.Usage)
+	assert.True(t, unique.NoUserModification)
+
+	// Object classes without a kind are structural.
+	oc := s.ObjectClass("nsAdminDomain")
+	require.NotNil(t, oc)
+	assert.Equal(t, KindStructural, oc.Kind)
+	assert.Equal(t, []string{"nsAdminDomainName"}, oc.May)
+
+	assert.Equal(t, "RFC 4512", s.ObjectClass("top").Extensions["X-ORIGIN"][0])
+}
+
+func TestLDAPSchema_ActiveDirectory(t *testing.T) {
+	s := loadSchemaDump(t, "ad.ldif")
+	require.NoError(t, s.ParseError())
+
+	// Active Directory quotes syntax OIDs.
+	account := s.AttributeType("samaccountname")
+	require.NotNil(t, account)
+	assert.Equal(t, "1.3.6.1.4.1.1466.115.121.1.15", account.Syntax)
+	assert.True(t, account.SingleValue)
+
+	memberOf := s.AttributeType("memberOf")
+	require.NotNil(t, memberOf)
+	assert.True(t, memberOf.NoUserModification)
+	assert.False(t, memberOf.SingleValue)
+
+	group := s.ObjectClass("group")
+	require.NotNil(t, group)
+	assert.Equal(t, []string{"groupType"}, group.Must)
+	assert.Equal(t, []string{"member", "sAMAccountName"}, group.May)
+	assert.Equal(t, []string{"objectClass"}, s.ObjectClass("top").Must)
+}
+
+func TestLDAPSchema_ParseError(t *testing.T) {
+	s := &LDAPSchema{
+		AttributeTypes: []string{
+			"( 2.5.4.3 NAME 'cn' )",
+			"( 2.5.4.4 NAME 'sn",
+			"2.5.4.5 NAME 'serialNumber'",
+		},
+	}
+
+	assert.Error(t, s.ParseError())
+	assert.NotNil(t, s.AttributeType("cn"))
+	assert.Nil(t, s.AttributeType("sn"))
+}
+
+func TestLDAPSchema_Copy(t *testing.T) {
+	s := LDAPSchema{AttributeTypes: []string{"( 2.5.4.3 NAME 'cn' )"}}
+
+	// Schemas hold no lock, so they can be copied before and after parsing.
+	before := s
+	assert.NotNil(t, before.AttributeType("cn"))
+	assert.NotNil(t, s.AttributeType("cn"))
+	after := s
+	assert.NotNil(t, after.AttributeType("cn"))
+}
+
+func TestParseAttributeType(t *testing.T) {
+	at, err := ParseAttributeType(`( 1.2.3 NAME 'test' DESC 'it\27s a \5Ctest' OBSOLETE SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{64} COLLECTIVE X-ORIGIN 'local' )`)
+	require.NoError(t, err)
+	assert.Equal(t, `it's a \test`, at.Description)
+	assert.True(t, at.Obsolete)
+	assert.True(t, at.Collective)
+	assert.Equal(t, 64, at.SyntaxLength)
+	assert.Equal(t, []string{"local"}, at.Extensions["X-ORIGIN"])
+
+	_, err = ParseAttributeType("( 1.2.3 NAME 'test' SYNTAX 1.2{x} )")
+	assert.Error(t, err)
+	_, err = ParseAttributeType("( 1.2.3 NAME ( 'a' 'b' )")
+	assert.Error(t, err)
+	_, err = ParseAttributeType("( 1.2.3 NAME )")
+	assert.Error(t, err)
+}
@@ -0,0 +1,386 @@
+package ldapx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ObjectClassKind is the kind of an object class.
+type ObjectClassKind int
+
+const (
+	KindStructural ObjectClassKind = iota // KindStructural is the kind of object classes that define an entry
+	KindAbstract                          // KindAbstract is the kind of object classes that are only inherited from
+	KindAuxiliary                         // KindAuxiliary is the kind of object classes that are added to an entry
+)
+
+// String returns the RFC 4512 keyword of the kind.
+func (k ObjectClassKind) String() string {
+	switch k {
+	case KindStructural:
+		return "STRUCTURAL"
+	case KindAbstract:
+		return "ABSTRACT"
+	case KindAuxiliary:
+		return "AUXILIARY"
+	}
+	return fmt.Sprintf("ObjectClassKind(%d)", int(k))
+}
+
+// AttributeUsage is the usage of an attribute type.
+type AttributeUsage string
+
+const (
+	UsageUserApplications     AttributeUsage = "userApplications"     // UsageUserApplications is the usage of user attributes
+	UsageDirectoryOperation   AttributeUsage = "directoryOperation"   // UsageDirectoryOperation is the usage of operational attributes
+	UsageDistributedOperation AttributeUsage = "distributedOperation" // UsageDistributedOperation is the usage of operational attributes shared between servers
+	UsageDSAOperation         AttributeUsage = "dSAOperation"         // UsageDSAOperation is the usage of operational attributes specific to a server
+)
+
+// AttributeType represents an attribute type. Equality, Ordering, Substr and
+// Syntax are inherited from the superior type when they are not given.
+type AttributeType struct {
+	OID                string              // Numeric OID, or a descriptive OID on some servers
+	Name               string              // First name
+	Names              []string            // Name and aliases
+	Description        string              // DESC
+	Obsolete           bool                // OBSOLETE
+	Sup                string              // Superior type
+	Equality           string              // Equality matching rule
+	Ordering           string              // Ordering matching rule
+	Substr             string              // Substrings matching rule
+	Syntax             string              // Syntax OID
+	SyntaxLength       int                 // Suggested maximum length, or 0
+	SingleValue        bool                // SINGLE-VALUE
+	Collective         bool                // COLLECTIVE
+	NoUserModification bool                // NO-USER-MODIFICATION
+	Usage              AttributeUsage      // USAGE, UsageUserApplications if not given
+	Extensions         map[string][]string // X- extensions keyed by name, such as X-ORIGIN
+}
+
+// ObjectClass represents an object class.
+type ObjectClass struct {
+	OID         string              // Numeric OID, or a descriptive OID on some servers
+	Name        string              // First name
+	Names       []string            // Name and aliases
+	Description string              // DESC
+	Obsolete    bool                // OBSOLETE
+	Sup         []string            // Superior classes
+	Kind        ObjectClassKind     // ABSTRACT, STRUCTURAL or AUXILIARY
+	Must        []string            // Required attribute types, not including those of superior classes
+	May         []string            // Allowed attribute types, not including those of superior classes
+	Extensions  map[string][]string // X- extensions keyed by name, such as X-ORIGIN
+}
+
+// LDAPSyntax represents an LDAP syntax.
+type LDAPSyntax struct {
+	OID         string              // Numeric OID
+	Description string              // DESC
+	Extensions  map[string][]string // X- extensions keyed by name, such as X-NOT-HUMAN-READABLE
+}
+
+// MatchingRule represents a matching rule.
+type MatchingRule struct {
+	OID         string              // Numeric OID
+	Name        string              // First name
+	Names       []string            // Name and aliases
+	Description string              // DESC
+	Obsolete    bool                // OBSOLETE
+	Syntax      string              // Syntax of the assertion value
+	Extensions  map[string][]string // X- extensions keyed by name
+}
+
+// MatchingRuleUse represents the attribute types a matching rule applies to.
+type MatchingRuleUse struct {
+	OID         string              // OID of the matching rule
+	Name        string              // First name
+	Names       []string            // Name and aliases
+	Description string              // DESC
+	Obsolete    bool                // OBSOLETE
+	Applies     []string            // Attribute types the rule applies to
+	Extensions  map[string][]string // X- extensions keyed by name
+}
+
+// ParseAttributeType parses an RFC 4512 attribute type description.
+func ParseAttributeType(def string) (*AttributeType, error) {
+	d, err := parseDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+
+	at := &AttributeType{
+		OID:                d.oid,
+		Names:              d.values("NAME"),
+		Description:        d.value("DESC"),
+		Obsolete:           d.has("OBSOLETE"),
+		Sup:                d.value("SUP"),
+		Equality:           d.value("EQUALITY"),
+		Ordering:           d.value("ORDERING"),
+		Substr:             d.value("SUBSTR"),
+		SingleValue:        d.has("SINGLE-VALUE"),
+		Collective:         d.has("COLLECTIVE"),
+		NoUserModification: d.has("NO-USER-MODIFICATION"),
+		Usage:              UsageUserApplications,
+		Extensions:         d.extensions,
+	}
+	if len(at.Names) > 0 {
+		at.Name = at.Names[0]
+	}
+	if usage := d.value("USAGE"); usage != "" {
+		at.Usage = AttributeUsage(usage)
+	}
+	if at.Syntax, at.SyntaxLength, err = parseSyntaxLength(d.value("SYNTAX")); err != nil {
+		return nil, fmt.Errorf("attribute type %s: %w", d.oid, err)
+	}
+	return at, nil
+}
+
+// ParseObjectClass parses an RFC 4512 object class description.
+func ParseObjectClass(def string) (*ObjectClass, error) {
+	d, err := parseDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+
+	oc := &ObjectClass{
+		OID:         d.oid,
+		Names:       d.values("NAME"),
+		Description: d.value("DESC"),
+		Obsolete:    d.has("OBSOLETE"),
+		Sup:         d.values("SUP"),
+		Must:        d.values("MUST"),
+		May:         d.values("MAY"),
+		Extensions:  d.extensions,
+	}
+	if len(oc.Names) > 0 {
+		oc.Name = oc.Names[0]
+	}
+	switch {
+	case d.has("ABSTRACT"):
+		oc.Kind = KindAbstract
+	case d.has("AUXILIARY"):
+		oc.Kind = KindAuxiliary
+	}
+	return oc, nil
+}
+
+// ParseLDAPSyntax parses an RFC 4512 LDAP syntax description.
+func ParseLDAPSyntax(def string) (*LDAPSyntax, error) {
+	d, err := parseDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+	return &LDAPSyntax{OID: d.oid, Description: d.value("DESC"), Extensions: d.extensions}, nil
+}
+
+// ParseMatchingRule parses an RFC 4512 matching rule description.
+func ParseMatchingRule(def string) (*MatchingRule, error) {
+	d, err := parseDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+
+	mr := &MatchingRule{
+		OID:         d.oid,
+		Names:       d.values("NAME"),
+		Description: d.value("DESC"),
+		Obsolete:    d.has("OBSOLETE"),
+		Syntax:      d.value("SYNTAX"),
+		Extensions:  d.extensions,
+	}
+	if len(mr.Names) > 0 {
+		mr.Name = mr.Names[0]
+	}
+	return mr, nil
+}
+
+// ParseMatchingRuleUse parses an RFC 4512 matching rule use description.
+func ParseMatchingRuleUse(def string) (*MatchingRuleUse, error) {
+	d, err := parseDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+
+	mru := &MatchingRuleUse{
+		OID:         d.oid,
+		Names:       d.values("NAME"),
+		Description: d.value("DESC"),
+		Obsolete:    d.has("OBSOLETE"),
+		Applies:     d.values("APPLIES"),
+		Extensions:  d.extensions,
+	}
+	if len(mru.Names) > 0 {
+		mru.Name = mru.Names[0]
+	}
+	return mru, nil
+}
+
+// parseSyntaxLength splits a syntax OID with an optional length, such as
+// "1.3.6.1.4.1.1466.115.121.1.15{256}".
+func parseSyntaxLength(s string) (string, int, error) {
+	i := strings.IndexByte(s, '{')
+	if i < 0 {
+		return s, 0, nil
+	}
+	if !strings.HasSuffix(s, "}") {
+		return "", 0, fmt.Errorf("invalid syntax length in %q", s)
+	}
+	n, err := strconv.Atoi(s[i+1 : len(s)-1])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid syntax length in %q", s)
+	}
+	return s[:i], n, nil
+}
+
+// flagKeywords are the keywords of RFC 4512 descriptions that take no value.
+var flagKeywords = map[string]bool{
+	"OBSOLETE":             true,
+	"SINGLE-VALUE":         true,
+	"COLLECTIVE":           true,
+	"NO-USER-MODIFICATION": true,
+	"ABSTRACT":             true,
+	"STRUCTURAL":           true,
+	"AUXILIARY":            true,
+}
+
+// definition is a parsed RFC 4512 description.
+type definition struct {
+	oid        string
+	fields     map[string][]string // Values keyed by upper-cased keyword; flags have no values
+	extensions map[string][]string // X- extensions keyed by name
+}
+
+// has returns true if the keyword is present.
+func (d *definition) has(keyword string) bool {
+	_, ok := d.fields[keyword]
+	return ok
+}
+
+// value returns the first value of the keyword.
+func (d *definition) value(keyword string) string {
+	if v := d.fields[keyword]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// values returns the values of the keyword.
+func (d *definition) values(keyword string) []string {
+	return d.fields[keyword]
+}
+
+// schemaToken is a token of an RFC 4512 description.
+type schemaToken struct {
+	text   string
+	quoted bool
+}
+
+// parseDefinition parses an RFC 4512 description into its OID and fields.
+// Quoted OIDs, as sent by Active Directory, and descriptive OIDs, as sent by
+// 389 Directory Server, are accepted.
+func parseDefinition(def string) (*definition, error) {
+	tokens, err := lexDefinition(def)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) < 3 || tokens[0] != (schemaToken{text: "("}) || tokens[len(tokens)-1] != (schemaToken{text: ")"}) {
+		return nil, fmt.Errorf("invalid schema definition %q: must be enclosed in parentheses", def)
+	}
+
+	d := &definition{oid: tokens[1].text, fields: make(map[string][]string)}
+	tokens = tokens[2 : len(tokens)-1]
+	for len(tokens) > 0 {
+		t := tokens[0]
+		tokens = tokens[1:]
+		if t.quoted || t.text == "(" || t.text == ")" || t.text == "$" {
+			return nil, fmt.Errorf("invalid schema definition %q: unexpected %q", def, t.text)
+		}
+		keyword := strings.ToUpper(t.text)
+
+		var values []string
+		if !flagKeywords[keyword] {
+			if len(tokens) == 0 {
+				return nil, fmt.Errorf("invalid schema definition %q: %s has no value", def, keyword)
+			}
+			values, tokens, err = parseValues(tokens)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema definition %q: %s: %w", def, keyword, err)
+			}
+		}
+
+		if strings.HasPrefix(keyword, "X-") {
+			if d.extensions == nil {
+				d.extensions = make(map[string][]string)
+			}
+			d.extensions[t.text] = append(d.extensions[t.text], values...)
+			continue
+		}
+		d.fields[keyword] = append(d.fields[keyword], values...)
+	}
+	return d, nil
+}
+
+// parseValues parses a single value or a parenthesised list of values
+// separated by whitespace or "$", returning the remaining tokens.
+func parseValues(tokens []schemaToken) ([]string, []schemaToken, error) {
+	if tokens[0].quoted || tokens[0].text != "(" {
+		return []string{tokens[0].text}, tokens[1:], nil
+	}
+
+	var values []string
+	for i := 1; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.quoted:
+			values = append(values, t.text)
+		case t.text == ")":
+			return values, tokens[i+1:], nil
+		case t.text == "$":
+		case t.text == "(":
+			return nil, nil, errors.New("nested list")
+		default:
+			values = append(values, t.text)
+		}
+	}
+	return nil, nil, errors.New("unterminated list")
+}
+
+// lexDefinition splits an RFC 4512 description into tokens. Quoted strings
+// have the \27 and \5C escapes of RFC 4512 decoded.
+func lexDefinition(def string) ([]schemaToken, error) {
+	var tokens []schemaToken
+	for i := 0; i < len(def); {
+		switch c := def[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '$':
+			tokens = append(tokens, schemaToken{text: string(c)})
+			i++
+		case c == '\'':
+			end := strings.IndexByte(def[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid schema definition %q: unterminated string", def)
+			}
+			tokens = append(tokens, schemaToken{text: unescapeQDString(def[i+1 : i+1+end]), quoted: true})
+			i += end + 2
+		default:
+			start := i
+			for i < len(def) && !strings.ContainsRune(" \t\n\r()$'", rune(def[i])) {
+				i++
+			}
+			tokens = append(tokens, schemaToken{text: def[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+// unescapeQDString decodes the escapes of an RFC 4512 quoted string.
+func unescapeQDString(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	r := strings.NewReplacer(`\27`, `'`, `\5C`, `\`, `\5c`, `\`)
+	return r.Replace(s)
+}
@@ -0,0 +1,190 @@
+package ldapx
+
+import (
+	"context"
+	"iter"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// SearchIterOption configures SearchIter.
+type SearchIterOption func(*searchIterOptions)
+
+// searchIterOptions holds the settings of SearchIter.
+type searchIterOptions struct {
+	summary *SearchSummary // Filled in when the iteration ends, if not nil
+}
+
+// SearchSummary holds what a streamed search returns besides its entries.
+type SearchSummary struct {
+	Referrals []string       // Referrals returned by the server
+	Controls  []ldap.Control // Controls returned with the last page
+}
+
+// WithSummary fills summary with the referrals and controls of the search
+// as the iteration goes. It is complete once the iteration ends.
+func WithSummary(summary *SearchSummary) SearchIterOption {
+	return func(o *searchIterOptions) {
+		o.summary = summary
+	}
+}
+
+// add records the referrals and controls of a page.
+func (s *SearchSummary) add(result *ldap.SearchResult) {
+	if s == nil {
+		return
+	}
+	s.Referrals = append(s.Referrals, result.Referrals...)
+	s.Controls = result.Controls
+}
+
+// SearchIter searches the LDAP server and returns the entries one at a time,
+// fetching pages of pageSize entries with the paged results control as the
+// iteration goes. A pageSize of 0 disables paging. Errors end the iteration.
+func (c *Conn) SearchIter(request *ldap.SearchRequest, pageSize uint32, opts ...SearchIterOption) iter.Seq2[*Entry, error] {
+	return c.SearchIterContext(context.Background(), request, pageSize, opts...)
+}
+
+// SearchIterContext searches the LDAP server and returns the entries one at a
+// time, fetching pages of pageSize entries with the paged results control as
+// the iteration goes. A pageSize of 0 disables paging. Errors end the iteration.
+//
+// The connection is held for the duration of the iteration and returned to
+// the pool when it ends, including when the caller stops early.
+func (c *Conn) SearchIterContext(ctx context.Context, request *ldap.SearchRequest, pageSize uint32, opts ...SearchIterOption) iter.Seq2[*Entry, error] {
+	o := &searchIterOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(yield func(*Entry, error) bool) {
+		if o.summary != nil {
+			*o.summary = SearchSummary{}
+		}
+
+		p := newPager(request, pageSize)
+		conn, result, err := c.firstPage(ctx, p)
+		for err == nil {
+			o.summary.add(result)
+			more := p.next(result)
+			for _, e := range result.Entries {
+				if !yield(NewEntryFromLdapEntry(e), nil) {
+					_ = c.closePager(ctx, conn, p)
+					return
+				}
+			}
+			if !more {
+				c.put(conn)
+				return
+			}
+			var broken bool
+			result, err, broken = c.searchPage(ctx, conn, p.request)
+			if err != nil {
+				c.release(conn, broken)
+			}
+		}
+		yield(nil, wrapError("search", request.BaseDN, err))
+	}
+}
+
+// pager tracks the paged results control of a paged search.
+type pager struct {
+	request *ldap.SearchRequest // Copy of the search request carrying the paging control
+	paging  *ldap.ControlPaging // Paging control, nil if paging is disabled
+}
+
+// newPager returns a pager for the request.
+// The request is copied so that the caller's controls are left alone.
+func newPager(request *ldap.SearchRequest, pageSize uint32) *pager {
+	r := *request
+	r.Controls = nil
+	for _, control := range request.Controls {
+		if control.GetControlType() != ldap.ControlTypePaging {
+			r.Controls = append(r.Controls, control)
+		}
+	}
+
+	p := &pager{request: &r}
+	if pageSize > 0 {
+		p.paging = ldap.NewControlPaging(pageSize)
+		r.Controls = append(r.Controls, p.paging)
+	}
+	return p
+}
+
+// cookie returns the cookie of the next page, or nil if there is none.
+func (p *pager) cookie() []byte {
+	if p.paging == nil {
+		return nil
+	}
+	return p.paging.Cookie
+}
+
+// next sets the cookie from the result and returns true if there is another page.
+func (p *pager) next(result *ldap.SearchResult) bool {
+	if p.paging == nil {
+		return false
+	}
+	control, ok := ldap.FindControl(result.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+	if !ok || len(control.Cookie) == 0 {
+		p.paging.SetCookie(nil)
+		return false
+	}
+	p.paging.SetCookie(control.Cookie)
+	return true
+}
+
+// firstPage gets a connection and fetches the first page. The first page can
+// be retried on another connection; later pages are tied to the connection
+// that holds the cookie.
+func (c *Conn) firstPage(ctx context.Context, p *pager) (*ldap.Conn, *ldap.SearchResult, error) {
+	var conn *ldap.Conn
+	result, err := c.withRetry(ctx, true, func() (interface{}, error) {
+		lc, err := c.getRead(ctx, p.request.BaseDN)
+		if err != nil {
+			return nil, err
+		}
+		result, err, broken := c.searchPage(ctx, lc, p.request)
+		if err != nil {
+			c.release(lc, broken)
+			return nil, err
+		}
+		conn = lc
+		return result, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, result.(*ldap.SearchResult), nil
+}
+
+// searchPage fetches one page with the connection. broken is true if the
+// connection can no longer be used.
+func (c *Conn) searchPage(ctx context.Context, conn *ldap.Conn, request *ldap.SearchRequest) (result *ldap.SearchResult, err error, broken bool) {
+	r, err, abandoned := runContext(ctx, conn, func(conn *ldap.Conn) (interface{}, error) {
+		return conn.Search(request)
+	})
+	err = connError(conn, err, abandoned)
+	if err != nil {
+		return nil, err, abandoned || isConnError(err)
+	}
+	return r.(*ldap.SearchResult), nil, false
+}
+
+// closePager tells the server to release the paged search, if it has more
+// pages, and returns the connection to its pool.
+func (c *Conn) closePager(ctx context.Context, conn *ldap.Conn, p *pager) error {
+	if len(p.cookie()) == 0 {
+		c.put(conn)
+		return nil
+	}
+
+	// A page size of 0 abandons the paged search (RFC 2696).
+	size := p.paging.PagingSize
+	p.paging.PagingSize = 0
+	_, err, broken := c.searchPage(ctx, conn, p.request)
+	c.release(conn, broken)
+	p.paging.PagingSize = size
+	p.paging.SetCookie(nil)
+	return err
+}
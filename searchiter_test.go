@@ -0,0 +1,116 @@
+package ldapx
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putPeople stores n person entries below ou=people,dc=example,dc=com.
+func putPeople(s *fakeServer, n int) {
+	s.put("ou=people,dc=example,dc=com", map[string][]string{"objectClass": {"organizationalUnit"}})
+	for i := range n {
+		s.put(fmt.Sprintf("uid=user%02d,ou=people,dc=example,dc=com", i), map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {fmt.Sprintf("user%02d", i)},
+		})
+	}
+}
+
+func TestConn_SearchIter(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 25)
+
+	conn, err := Open(s.url(), WithPingOnReuse(false))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=people,dc=example,dc=com", ldap.ScopeSingleLevel, ldap.DerefAlways, 0, 0, false, "(objectClass=person)", nil, nil)
+	before := s.searches.Load()
+
+	var uids []string
+	for entry, err := range conn.SearchIter(request, 10) {
+		require.NoError(t, err)
+		uids = append(uids, entry.GetAttributeValue("uid"))
+	}
+
+	assert.Len(t, uids, 25)
+	assert.Equal(t, "user00", uids[0])
+	assert.Equal(t, "user24", uids[24])
+	assert.Equal(t, int32(3), s.searches.Load()-before)
+	assert.Empty(t, request.Controls)
+}
+
+func TestConn_SearchIterBreak(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 25)
+
+	conn, err := Open(s.url(), WithPoolSize(0, 1, 1), WithGetTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=people,dc=example,dc=com", ldap.ScopeSingleLevel, ldap.DerefAlways, 0, 0, false, "(objectClass=person)", nil, nil)
+
+	n := 0
+	for _, err := range conn.SearchIter(request, 10) {
+		require.NoError(t, err)
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, n)
+	assert.Equal(t, int32(1), s.abandons.Load())
+
+	// The only connection in the pool must have been returned.
+	_, err = conn.Lookup("ou=people,dc=example,dc=com")
+	assert.NoError(t, err)
+}
+
+func TestConn_SearchIterSummary(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 2)
+	s.put("ou=remote,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass": {"referral"},
+		"ref":         {"ldap://remote.example.com/ou=remote,ou=people,dc=example,dc=com"},
+	})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=people,dc=example,dc=com", ldap.ScopeSingleLevel, ldap.DerefAlways, 0, 0, false, "(objectClass=*)", nil, nil)
+
+	var summary SearchSummary
+	n := 0
+	for _, err := range conn.SearchIter(request, 1, WithSummary(&summary)) {
+		require.NoError(t, err)
+		n++
+	}
+
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"ldap://remote.example.com/ou=remote,ou=people,dc=example,dc=com"}, summary.Referrals)
+	assert.NotNil(t, ldap.FindControl(summary.Controls, ldap.ControlTypePaging))
+}
+
+func TestConn_SearchIterError(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=missing,dc=example,dc=com", ldap.ScopeWholeSubtree, ldap.DerefAlways, 0, 0, false, "(objectClass=*)", nil, nil)
+
+	var errs []error
+	for entry, err := range conn.SearchIter(request, 10) {
+		assert.Nil(t, entry)
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	assert.True(t, IsNotFound(errs[0]))
+}
@@ -0,0 +1,178 @@
+package ldapx
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ServerPolicy controls the order in which servers are dialed.
+type ServerPolicy int
+
+const (
+	PolicyFailover   ServerPolicy = iota // PolicyFailover dials the servers in the order given
+	PolicyRoundRobin                     // PolicyRoundRobin rotates through the servers
+	PolicyRandom                         // PolicyRandom dials the servers in random order
+)
+
+// String returns the name of the policy.
+func (p ServerPolicy) String() string {
+	switch p {
+	case PolicyFailover:
+		return "failover"
+	case PolicyRoundRobin:
+		return "round-robin"
+	case PolicyRandom:
+		return "random"
+	}
+	return fmt.Sprintf("ServerPolicy(%d)", int(p))
+}
+
+// WithServers adds servers to the list given to Open.
+func WithServers(urls ...string) Option {
+	return func(c *config) {
+		c.servers = append(c.servers, urls...)
+	}
+}
+
+// WithServerPolicy sets the order in which servers are dialed.
+func WithServerPolicy(policy ServerPolicy) Option {
+	return func(c *config) {
+		c.serverPolicy = policy
+	}
+}
+
+// WithProbeInterval sets how often servers that are marked down are probed.
+// Zero disables probing; down servers are then only retried when no healthy
+// server is left.
+func WithProbeInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.probeInterval = d
+	}
+}
+
+// splitURLs splits a space-separated list of LDAP URLs, as accepted by
+// OpenLDAP's uri option.
+func splitURLs(urls string) []string {
+	return strings.Fields(urls)
+}
+
+// server is an LDAP server in a serverSet.
+type server struct {
+	url  string      // LDAP URL of the server
+	down atomic.Bool // down is true if the server failed to dial or ping
+}
+
+// serverSet is the set of servers a Conn connects to.
+type serverSet struct {
+	servers []*server
+	policy  ServerPolicy
+	next    atomic.Uint32 // Next server for round-robin
+	stop    chan struct{} // Closed to stop probing
+	once    sync.Once
+}
+
+// newServerSet creates a server set for the given URLs.
+func newServerSet(urls []string, policy ServerPolicy) (*serverSet, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoServers
+	}
+
+	s := &serverSet{policy: policy, stop: make(chan struct{})}
+	for _, u := range urls {
+		s.servers = append(s.servers, &server{url: u})
+	}
+	return s, nil
+}
+
+// candidates returns the servers in the order they should be dialed. Healthy
+// servers come first; servers marked down are kept as a last resort.
+func (s *serverSet) candidates() []*server {
+	n := len(s.servers)
+	order := make([]*server, 0, n)
+
+	switch s.policy {
+	case PolicyRoundRobin:
+		start := int(s.next.Add(1)-1) % n
+		for i := range n {
+			order = append(order, s.servers[(start+i)%n])
+		}
+	case PolicyRandom:
+		for _, i := range rand.Perm(n) {
+			order = append(order, s.servers[i])
+		}
+	default:
+		order = append(order, s.servers...)
+	}
+
+	healthy := make([]*server, 0, n)
+	var down []*server
+	for _, srv := range order {
+		if srv.down.Load() {
+			down = append(down, srv)
+		} else {
+			healthy = append(healthy, srv)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// dial dials the first server that accepts a connection, marking servers that
+// fail as down.
+func (s *serverSet) dial(dial func(url string) (*ldap.Conn, error)) (*ldap.Conn, *server, error) {
+	var errs []error
+	for _, srv := range s.candidates() {
+		conn, err := dial(srv.url)
+		if err == nil {
+			srv.down.Store(false)
+			return conn, srv, nil
+		}
+		srv.down.Store(true)
+		errs = append(errs, fmt.Errorf("%s: %w", srv.url, err))
+	}
+	return nil, nil, errors.Join(errs...)
+}
+
+// probe periodically dials the servers that are marked down and marks them
+// up again once they answer.
+func (s *serverSet) probe(interval time.Duration, dial func(url string) (*ldap.Conn, error)) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for _, srv := range s.servers {
+			if !srv.down.Load() {
+				continue
+			}
+			conn, err := dial(srv.url)
+			if err != nil {
+				continue
+			}
+			if pingConnection(conn) == nil {
+				srv.down.Store(false)
+			}
+			conn.Close()
+		}
+	}
+}
+
+// close stops probing.
+func (s *serverSet) close() {
+	s.once.Do(func() { close(s.stop) })
+}
@@ -0,0 +1,86 @@
+package ldapx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_Failover(t *testing.T) {
+	s1 := newFakeServer(t)
+	s2 := newFakeServer(t)
+
+	conn, err := Open(s1.url()+" "+s2.url(), WithProbeInterval(0))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Lookup("")
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, s2.dials.Load())
+
+	s1.stop()
+
+	_, err = conn.Lookup("")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, s2.dials.Load())
+	assert.True(t, conn.servers.servers[0].down.Load())
+	assert.False(t, conn.servers.servers[1].down.Load())
+}
+
+func TestOpen_FailoverDialError(t *testing.T) {
+	s1 := newFakeServer(t)
+	s2 := newFakeServer(t)
+	s1.stop()
+
+	conn, err := Open(s1.url(), WithServers(s2.url()), WithProbeInterval(0))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.True(t, conn.servers.servers[0].down.Load())
+	assert.EqualValues(t, 1, s2.dials.Load())
+}
+
+func TestOpen_RoundRobin(t *testing.T) {
+	s1 := newFakeServer(t)
+	s2 := newFakeServer(t)
+
+	conn, err := Open(s1.url()+" "+s2.url(), WithServerPolicy(PolicyRoundRobin), WithPoolSize(0, 3, 3))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Open took the first connection; hold two more so both must be dialed.
+	lc1, err := conn.get(context.Background())
+	require.NoError(t, err)
+	lc2, err := conn.get(context.Background())
+	require.NoError(t, err)
+	lc3, err := conn.get(context.Background())
+	require.NoError(t, err)
+	conn.put(lc1)
+	conn.put(lc2)
+	conn.put(lc3)
+
+	assert.EqualValues(t, 2, s1.dials.Load())
+	assert.EqualValues(t, 1, s2.dials.Load())
+}
+
+func TestOpen_ProbeMarksServerUp(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithProbeInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	conn.servers.servers[0].down.Store(true)
+
+	assert.Eventually(t, func() bool {
+		return !conn.servers.servers[0].down.Load()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestOpen_NoServers(t *testing.T) {
+	_, err := Open(" ")
+	assert.ErrorIs(t, err, ErrNoServers)
+}
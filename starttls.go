@@ -0,0 +1,100 @@
+package ldapx
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// StartTLSMode controls whether StartTLS is negotiated on ldap:// connections.
+type StartTLSMode int
+
+const (
+	StartTLSOff           StartTLSMode = iota // StartTLSOff never negotiates StartTLS
+	StartTLSOpportunistic                     // StartTLSOpportunistic negotiates StartTLS and falls back to cleartext if it fails
+	StartTLSRequired                          // StartTLSRequired negotiates StartTLS and fails if it cannot
+)
+
+// String returns the name of the mode.
+func (m StartTLSMode) String() string {
+	switch m {
+	case StartTLSOff:
+		return "off"
+	case StartTLSOpportunistic:
+		return "opportunistic"
+	case StartTLSRequired:
+		return "required"
+	}
+	return fmt.Sprintf("StartTLSMode(%d)", int(m))
+}
+
+// WithStartTLS sets the StartTLS mode used for ldap:// URLs. It has no effect
+// on ldaps:// URLs, which are encrypted from the start.
+func WithStartTLS(mode StartTLSMode) Option {
+	return func(c *config) {
+		c.startTLS = mode
+	}
+}
+
+// dialStartTLS dials the LDAP server and negotiates StartTLS according to mode.
+func dialStartTLS(rawURL string, tlsConfig *tls.Config, mode StartTLSMode, timeout time.Duration) (*ldap.Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig = serverTLSConfig(tlsConfig, u.Hostname())
+
+	conn, err := dialURL(rawURL, tlsConfig, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if mode == StartTLSOff || u.Scheme != "ldap" {
+		return conn, nil
+	}
+
+	err = conn.StartTLS(tlsConfig)
+	if err == nil {
+		return conn, nil
+	}
+	conn.Close()
+
+	if mode == StartTLSRequired {
+		return nil, fmt.Errorf("starttls error: %w", err)
+	}
+
+	// A failed negotiation may leave the connection unusable, so start over in cleartext.
+	return dialURL(rawURL, tlsConfig, timeout)
+}
+
+// serverTLSConfig returns the TLS configuration used for the server. The
+// server name defaults to the host being dialed, so that each server of a
+// set is verified against its own name.
+func serverTLSConfig(tlsConfig *tls.Config, host string) *tls.Config {
+	if tlsConfig == nil {
+		return &tls.Config{ServerName: host} //nolint: gosec
+	}
+	if tlsConfig.ServerName != "" {
+		return tlsConfig
+	}
+	cfg := tlsConfig.Clone()
+	cfg.ServerName = host
+	return cfg
+}
+
+// checkSecure returns ErrCleartextBind if StartTLS is required and the
+// connection is not encrypted.
+func checkSecure(conn *ldap.Conn, rawURL string, mode StartTLSMode) error {
+	if mode != StartTLSRequired {
+		return nil
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme == "ldapi" {
+		return nil
+	}
+	if _, ok := conn.TLSConnectionState(); !ok {
+		return ErrCleartextBind
+	}
+	return nil
+}
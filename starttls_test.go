@@ -0,0 +1,85 @@
+package ldapx
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_StartTLSRequired(t *testing.T) {
+	s := newFakeServer(t)
+	tlsConfig := s.enableTLS()
+
+	conn, err := Open(s.url(), WithTLSConfig(tlsConfig), WithStartTLS(StartTLSRequired))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	lc, err := conn.get(context.Background())
+	require.NoError(t, err)
+	defer conn.put(lc)
+
+	_, ok := lc.TLSConnectionState()
+	assert.True(t, ok)
+}
+
+func TestOpen_StartTLSRequiredUnsupported(t *testing.T) {
+	s := newFakeServer(t)
+
+	_, err := Open(s.url(), WithBind("cn=admin", "secret"), WithStartTLS(StartTLSRequired))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "starttls")
+	assert.EqualValues(t, 0, s.binds.Load())
+}
+
+func TestOpen_StartTLSOpportunisticFallback(t *testing.T) {
+	s := newFakeServer(t)
+
+	conn, err := Open(s.url(), WithBind("cn=admin", "secret"), WithStartTLS(StartTLSOpportunistic))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.EqualValues(t, 1, s.binds.Load())
+	assert.EqualValues(t, 2, s.dials.Load())
+}
+
+func TestCheckSecure(t *testing.T) {
+	s := newFakeServer(t)
+
+	lc, err := dialURL(s.url(), nil, 0)
+	require.NoError(t, err)
+	defer lc.Close()
+
+	assert.True(t, errors.Is(checkSecure(lc, s.url(), StartTLSRequired), ErrCleartextBind))
+	assert.NoError(t, checkSecure(lc, s.url(), StartTLSOpportunistic))
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	assert.Equal(t, "ldap1.example.com", serverTLSConfig(nil, "ldap1.example.com").ServerName)
+
+	shared := &tls.Config{MinVersion: tls.VersionTLS12}
+	cfg := serverTLSConfig(shared, "ldap2.example.com")
+	assert.Equal(t, "ldap2.example.com", cfg.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Empty(t, shared.ServerName)
+
+	explicit := &tls.Config{ServerName: "ldap.example.com"}
+	assert.Same(t, explicit, serverTLSConfig(explicit, "ldap2.example.com"))
+}
+
+func TestOpen_StartTLSServerName(t *testing.T) {
+	s := newFakeServer(t)
+	tlsConfig := s.enableTLS()
+
+	// Each server is verified against its own host name, not including the port.
+	conn, err := Open(s.url()+" "+s.url(), WithTLSConfig(tlsConfig), WithStartTLS(StartTLSRequired), WithServerPolicy(PolicyRoundRobin))
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Empty(t, tlsConfig.ServerName)
+
+	_, err = conn.RootDSE()
+	require.NoError(t, err)
+}
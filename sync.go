@@ -13,10 +13,15 @@ func (e *Entry) SyncAttributeValues(attr string, values []string) {
 
 // SyncAttributeValuesIgnoreCase will add and remove values from the attribute to
 // match the values provided, ignoring case if ignoreCase is true.
+// A bound entry uses the equality matching rule of the attribute unless
+// ignoreCase is true.
 func (e *Entry) SyncAttributeValuesIgnoreCase(attr string, values []string, ignoreCase bool) {
-	if ignoreCase {
+	switch {
+	case ignoreCase:
 		e.syncAttributeValuesIgnoreCase(attr, values)
-	} else {
+	case e.schema != nil:
+		e.syncAttributeValuesMatching(attr, values)
+	default:
 		e.syncAttributeValuesCaseSensitive(attr, values)
 	}
 }
@@ -40,3 +45,29 @@ func (e *Entry) syncAttributeValuesCaseSensitive(attr string, value []string) {
 	e.AddAttributeValues(attr, newValues.Difference(currentValues).ToSlice())
 	e.DeleteAttributeValues(attr, currentValues.Difference(newValues).ToSlice())
 }
+
+// syncAttributeValuesMatching will add and remove values from the attribute
+// to match the values provided, comparing values with the equality matching
+// rule of the attribute.
+func (e *Entry) syncAttributeValuesMatching(attr string, value []string) {
+	normalize := e.valueNormalizer(attr, false)
+	currentValues := e.GetAttributeValues(attr)
+
+	e.AddAttributeValues(attr, valuesNotIn(value, currentValues, normalize))
+	e.DeleteAttributeValues(attr, valuesNotIn(currentValues, value, normalize))
+}
+
+// valuesNotIn returns the values of x that match no value of y.
+func valuesNotIn(x, y []string, normalize normalizer) []string {
+	in := make(map[string]bool, len(y))
+	for _, v := range y {
+		in[normalize(v)] = true
+	}
+	var out []string
+	for _, v := range x {
+		if !in[normalize(v)] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package ldapx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// The typed helpers decode entries into values of type T, which must be a
+// struct or a pointer to a struct with ldap tags (see Entry.Unmarshal). Only
+// the attributes T maps are requested from the server.
+
+// typeAttributes returns the attributes mapped by the fields of T. "1.1" is
+// returned if T only maps the DN, so that no attributes are requested.
+func typeAttributes[T any]() ([]string, error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ldapx: %s is not a struct or a pointer to a struct", reflect.TypeFor[T]())
+	}
+
+	fields, err := structFields(t)
+	if err != nil {
+		return nil, err
+	}
+	var attributes []string
+	for _, f := range fields {
+		if f.attr != "dn" {
+			attributes = append(attributes, f.attr)
+		}
+	}
+	if len(attributes) == 0 {
+		return []string{"1.1"}, nil
+	}
+	return attributes, nil
+}
+
+// decodeAs unmarshals the entry into a new value of type T.
+func decodeAs[T any](e *Entry) (T, error) {
+	var v T
+	target := any(&v)
+	if rv := reflect.ValueOf(&v).Elem(); rv.Kind() == reflect.Pointer {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		target = v
+	}
+	if err := e.Unmarshal(target); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// typedRequest returns a copy of the request that asks for the attributes of
+// T, unless the request names its own attributes.
+func typedRequest[T any](request *ldap.SearchRequest) (*ldap.SearchRequest, error) {
+	attributes, err := typeAttributes[T]()
+	if err != nil {
+		return nil, err
+	}
+	if len(request.Attributes) > 0 {
+		return request, nil
+	}
+	r := *request
+	r.Attributes = attributes
+	return &r, nil
+}
+
+// LookupAs looks up the entry with the given DN and decodes it into a T.
+func LookupAs[T any](c Client, dn string) (T, error) {
+	var zero T
+	attributes, err := typeAttributes[T]()
+	if err != nil {
+		return zero, err
+	}
+	entry, err := c.Lookup(dn, WithAttributes(attributes...))
+	if err != nil {
+		return zero, err
+	}
+	return decodeAs[T](entry)
+}
+
+// LookupAsContext looks up the entry with the given DN and decodes it into a T.
+func LookupAsContext[T any](ctx context.Context, c ClientContext, dn string) (T, error) {
+	var zero T
+	attributes, err := typeAttributes[T]()
+	if err != nil {
+		return zero, err
+	}
+	entry, err := c.LookupContext(ctx, dn, WithAttributes(attributes...))
+	if err != nil {
+		return zero, err
+	}
+	return decodeAs[T](entry)
+}
+
+// SearchAs searches the LDAP server and decodes the entries into values of
+// type T. If the request names no attributes, those of T are requested.
+func SearchAs[T any](c Client, request *ldap.SearchRequest) ([]T, error) {
+	request, err := typedRequest[T](request)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.Search(request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResult[T](result)
+}
+
+// SearchAsContext searches the LDAP server and decodes the entries into
+// values of type T. If the request names no attributes, those of T are
+// requested.
+func SearchAsContext[T any](ctx context.Context, c ClientContext, request *ldap.SearchRequest) ([]T, error) {
+	request, err := typedRequest[T](request)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.SearchContext(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResult[T](result)
+}
+
+// decodeResult decodes the entries of a search result.
+func decodeResult[T any](result *ldap.SearchResult) ([]T, error) {
+	values := make([]T, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		v, err := decodeAs[T](NewEntryFromLdapEntry(e))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.DN, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// FindOneAs searches the subtree at the given DN for the one entry that
+// matches the filter and decodes it into a T. An ErrNotFound error is
+// returned if no entry matches and ErrMultipleEntries if more than one does.
+func FindOneAs[T any](c Client, dn string, filter string) (T, error) {
+	var zero T
+	attributes, err := typeAttributes[T]()
+	if err != nil {
+		return zero, err
+	}
+	entry, err := c.FindEntry(dn, filter, attributes)
+	if err != nil {
+		return zero, err
+	}
+	return decodeAs[T](entry)
+}
+
+// FindOneAsContext searches the subtree at the given DN for the one entry
+// that matches the filter and decodes it into a T.
+func FindOneAsContext[T any](ctx context.Context, c ClientContext, dn string, filter string) (T, error) {
+	var zero T
+	attributes, err := typeAttributes[T]()
+	if err != nil {
+		return zero, err
+	}
+	entry, err := c.FindEntryContext(ctx, dn, filter, attributes)
+	if err != nil {
+		return zero, err
+	}
+	return decodeAs[T](entry)
+}
+
+// ExecuteT executes a function with a connection from the pool and returns
+// its result without a type assertion.
+func ExecuteT[T any](c *Conn, f func(*Conn) (T, error)) (T, error) {
+	return ExecuteTContext(context.Background(), c, f)
+}
+
+// ExecuteTContext executes a function with a connection from the pool and
+// returns its result without a type assertion. The connection is dropped if
+// the context is done before the function returns.
+func ExecuteTContext[T any](ctx context.Context, c *Conn, f func(*Conn) (T, error)) (T, error) {
+	result, err := c.ExecuteContext(ctx, func(conn *Conn) (interface{}, error) {
+		return f(conn)
+	})
+	v, _ := result.(T)
+	return v, err
+}
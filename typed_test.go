@@ -0,0 +1,122 @@
+package ldapx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	DN   string   `ldap:"dn"`
+	UID  string   `ldap:"uid"`
+	CN   string   `ldap:"cn"`
+	Mail []string `ldap:"mail,omitempty"`
+}
+
+func TestTypeAttributes(t *testing.T) {
+	attributes, err := typeAttributes[testUser]()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uid", "cn", "mail"}, attributes)
+
+	attributes, err = typeAttributes[*testUser]()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uid", "cn", "mail"}, attributes)
+
+	attributes, err = typeAttributes[struct {
+		DN string `ldap:"dn"`
+	}]()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.1"}, attributes)
+
+	_, err = typeAttributes[string]()
+	assert.Error(t, err)
+}
+
+func TestLookupAs(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("uid=jdoe,dc=example,dc=com", map[string][]string{
+		"objectClass": {"person"}, "uid": {"jdoe"}, "cn": {"John Doe"}, "sn": {"Doe"}, "mail": {"jdoe@example.com"},
+	})
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	user, err := LookupAs[testUser](conn, "uid=jdoe,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.Equal(t, testUser{DN: "uid=jdoe,dc=example,dc=com", UID: "jdoe", CN: "John Doe", Mail: []string{"jdoe@example.com"}}, user)
+
+	ptr, err := LookupAsContext[*testUser](context.Background(), conn, "uid=jdoe,dc=example,dc=com")
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe", ptr.UID)
+
+	_, err = LookupAs[testUser](conn, "uid=missing,dc=example,dc=com")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestSearchAs(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 3)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request := NewSearchRequest("ou=people,dc=example,dc=com", ldap.ScopeWholeSubtree, ldap.DerefAlways, 0, 0, false, "(objectClass=person)", nil, nil)
+	users, err := SearchAs[testUser](conn, request)
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+	assert.Equal(t, "uid=user00,ou=people,dc=example,dc=com", users[0].DN)
+	assert.Equal(t, "user00", users[0].UID)
+	assert.Nil(t, request.Attributes)
+
+	// Only the attributes of the type are fetched.
+	type onlyDN struct {
+		DN  string `ldap:"dn"`
+		UID string `ldap:"uid"`
+	}
+	dns, err := SearchAsContext[onlyDN](context.Background(), conn, request)
+	require.NoError(t, err)
+	assert.Equal(t, "user01", dns[1].UID)
+}
+
+func TestFindOneAs(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 3)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	user, err := FindOneAs[testUser](conn, "ou=people,dc=example,dc=com", "(uid=user02)")
+	require.NoError(t, err)
+	assert.Equal(t, "uid=user02,ou=people,dc=example,dc=com", user.DN)
+
+	_, err = FindOneAs[testUser](conn, "ou=people,dc=example,dc=com", "(uid=nobody)")
+	assert.True(t, IsNotFound(err))
+
+	_, err = FindOneAs[testUser](conn, "ou=people,dc=example,dc=com", "(objectClass=person)")
+	assert.ErrorIs(t, err, ErrMultipleEntries)
+}
+
+func TestExecuteT(t *testing.T) {
+	s := newFakeServer(t)
+	putPeople(s, 2)
+
+	conn, err := Open(s.url())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	n, err := ExecuteT(conn, func(c *Conn) (int, error) {
+		result, err := c.QuickSearch("ou=people,dc=example,dc=com", "(objectClass=person)", []string{"1.1"})
+		if err != nil {
+			return 0, err
+		}
+		return len(result.Entries), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
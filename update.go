@@ -1,11 +1,18 @@
 package ldapx
 
+import "context"
+
 // EntryUpdateFunc is a function that can be used to update an entry.
 type EntryUpdateFunc func(*Entry) (*Entry, error)
 
 // UpdateEntry updates the entry with the given DN using the given function.
 func (c *Conn) UpdateEntry(dn string, f EntryUpdateFunc) error {
-	entry, err := c.LookupOrNew(dn)
+	return c.UpdateEntryContext(context.Background(), dn, f)
+}
+
+// UpdateEntryContext updates the entry with the given DN using the given function.
+func (c *Conn) UpdateEntryContext(ctx context.Context, dn string, f EntryUpdateFunc) error {
+	entry, err := c.LookupOrNewContext(ctx, dn)
 	if err != nil {
 		return err
 	}
@@ -15,7 +22,7 @@ func (c *Conn) UpdateEntry(dn string, f EntryUpdateFunc) error {
 	}
 
 	if entry.Changed() {
-		return entry.Update(c)
+		return entry.UpdateContext(ctx, c)
 	}
 	return nil
 }
@@ -24,3 +31,8 @@ func (c *Conn) UpdateEntry(dn string, f EntryUpdateFunc) error {
 func (c *Conn) Update(entry *Entry) error {
 	return entry.Update(c)
 }
+
+// UpdateContext updates the given entry.
+func (c *Conn) UpdateContext(ctx context.Context, entry *Entry) error {
+	return entry.UpdateContext(ctx, c)
+}
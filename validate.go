@@ -0,0 +1,248 @@
+package ldapx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ViolationKind is the kind of a schema violation.
+type ViolationKind int
+
+const (
+	UnknownObjectClass  ViolationKind = iota // UnknownObjectClass means an object class is not defined in the schema
+	UnknownAttribute                         // UnknownAttribute means an attribute type is not defined in the schema
+	MissingAttribute                         // MissingAttribute means an attribute required by an object class is missing
+	NotAllowedAttribute                      // NotAllowedAttribute means no object class of the entry allows the attribute
+	MultipleValues                           // MultipleValues means a single-valued attribute has more than one value
+	NoUserModification                       // NoUserModification means a change writes to an attribute users may not modify
+)
+
+// String returns the name of the kind.
+func (k ViolationKind) String() string {
+	switch k {
+	case UnknownObjectClass:
+		return "unknown object class"
+	case UnknownAttribute:
+		return "unknown attribute"
+	case MissingAttribute:
+		return "missing required attribute"
+	case NotAllowedAttribute:
+		return "attribute not allowed"
+	case MultipleValues:
+		return "multiple values for single-valued attribute"
+	case NoUserModification:
+		return "attribute not user-modifiable"
+	}
+	return fmt.Sprintf("ViolationKind(%d)", int(k))
+}
+
+// SchemaViolation describes how an entry does not conform to the schema.
+type SchemaViolation struct {
+	Kind        ViolationKind // Kind of violation
+	Attribute   string        // Attribute concerned, if any
+	ObjectClass string        // Object class concerned, if any
+}
+
+// String describes the violation.
+func (v SchemaViolation) String() string {
+	switch {
+	case v.Kind == MissingAttribute:
+		return fmt.Sprintf("%s %s required by %s", v.Kind, v.Attribute, v.ObjectClass)
+	case v.ObjectClass != "":
+		return fmt.Sprintf("%s %s", v.Kind, v.ObjectClass)
+	}
+	return fmt.Sprintf("%s %s", v.Kind, v.Attribute)
+}
+
+// ValidationError is the error returned when an entry does not conform to the
+// schema. It matches ErrConstraintViolation.
+type ValidationError struct {
+	Violations []SchemaViolation
+}
+
+// Error returns the error message.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.String())
+	}
+	return "schema violation: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether the target is ErrConstraintViolation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrConstraintViolation
+}
+
+// WithSchemaValidation validates entries against the server schema before
+// Update sends them, so that violations are reported without a round trip.
+func WithSchemaValidation() Option {
+	return func(c *config) {
+		c.validate = true
+	}
+}
+
+// Validate checks the entry, with its pending changes applied, against the
+// schema. It returns nil if the entry conforms. Entries being deleted are
+// not checked.
+//
+// Entries being updated may have been looked up with only some of their
+// attributes, so only the attributes named by the changes are checked.
+// Object class rules are then only checked if the entry has its object
+// classes: attributes that are changed must be allowed, and required
+// attributes may not be deleted. If the object classes themselves are
+// changed, all of their required attributes must be present.
+func (e *Entry) Validate(schema *LDAPSchema) []SchemaViolation {
+	if e.ChangeType == ChangeDelete {
+		return nil
+	}
+
+	var violations []SchemaViolation
+
+	// Work out which attributes are checked.
+	update := e.ChangeType == ChangeUpdate
+	names := e.AttributeNames()
+	classesChanged := false
+	if update {
+		names = e.changedAttributeNames()
+		for _, name := range names {
+			classesChanged = classesChanged || strings.EqualFold(name, "objectClass")
+		}
+	}
+	sort.Strings(names)
+	checkClasses := !update || classesChanged || e.AttributeExists("objectClass")
+
+	// Collect the attribute types allowed and required by the object classes
+	// and their superclasses.
+	allowed := make(map[*AttributeType]bool)
+	type requirement struct {
+		at          *AttributeType
+		name        string
+		objectClass string
+	}
+	var required []requirement
+	extensible := false
+	visited := make(map[*ObjectClass]bool)
+
+	var visit func(oc *ObjectClass, from string)
+	visit = func(oc *ObjectClass, from string) {
+		if visited[oc] {
+			return
+		}
+		visited[oc] = true
+		if strings.EqualFold(oc.Name, "extensibleObject") {
+			extensible = true
+		}
+		for _, name := range oc.Must {
+			at := schema.AttributeType(name)
+			if at != nil {
+				allowed[at] = true
+			}
+			required = append(required, requirement{at: at, name: name, objectClass: from})
+		}
+		for _, name := range oc.May {
+			if at := schema.AttributeType(name); at != nil {
+				allowed[at] = true
+			}
+		}
+		for _, sup := range oc.Sup {
+			if s := schema.ObjectClass(sup); s != nil {
+				visit(s, from)
+			}
+		}
+	}
+
+	if checkClasses {
+		for _, name := range e.GetAttributeValues("objectClass") {
+			oc := schema.ObjectClass(name)
+			if oc == nil {
+				violations = append(violations, SchemaViolation{Kind: UnknownObjectClass, ObjectClass: name})
+				continue
+			}
+			visit(oc, name)
+		}
+	}
+
+	// Index the attributes of the entry by attribute type.
+	present := make(map[*AttributeType]bool)
+	for _, name := range e.AttributeNames() {
+		if at := schema.AttributeType(name); at != nil {
+			present[at] = true
+		}
+	}
+
+	checked := make(map[*AttributeType]bool)
+	checkedNames := make(map[string]bool)
+	for _, name := range names {
+		checkedNames[strings.ToLower(name)] = true
+		at := schema.AttributeType(name)
+		if at == nil {
+			violations = append(violations, SchemaViolation{Kind: UnknownAttribute, Attribute: name})
+			continue
+		}
+		checked[at] = true
+		if !e.AttributeExists(name) {
+			continue
+		}
+
+		// Operational attributes are not governed by object classes.
+		if checkClasses && !allowed[at] && !extensible && at.Usage == UsageUserApplications {
+			violations = append(violations, SchemaViolation{Kind: NotAllowedAttribute, Attribute: name})
+		}
+		if at.SingleValue && len(e.GetAttributeValues(name)) > 1 {
+			violations = append(violations, SchemaViolation{Kind: MultipleValues, Attribute: name})
+		}
+	}
+
+	reported := make(map[string]bool)
+	for _, r := range required {
+		// Updates that keep the object classes only need to keep the
+		// required attributes they change.
+		if update && !classesChanged && !(r.at != nil && checked[r.at] || r.at == nil && checkedNames[strings.ToLower(r.name)]) {
+			continue
+		}
+		if r.at != nil && present[r.at] || r.at == nil && e.AttributeExists(r.name) {
+			continue
+		}
+		key := strings.ToLower(r.name)
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+		violations = append(violations, SchemaViolation{Kind: MissingAttribute, Attribute: r.name, ObjectClass: r.objectClass})
+	}
+
+	for _, change := range e.Changes {
+		if at := schema.AttributeType(change.Attr); at != nil && at.NoUserModification {
+			key := "nousermod:" + strings.ToLower(change.Attr)
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
+			violations = append(violations, SchemaViolation{Kind: NoUserModification, Attribute: change.Attr})
+		}
+	}
+
+	return violations
+}
+
+// changedAttributeNames returns the names of the attributes the pending
+// changes apply to, once each.
+func (e *Entry) changedAttributeNames() []string {
+	seen := make(map[string]bool, len(e.Changes))
+	var names []string
+	for _, change := range e.Changes {
+		key := strings.ToLower(change.Attr)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		name := change.Attr
+		if a := e.Attributes.Get(change.Attr); a != nil {
+			name = a.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,233 @@
+package ldapx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntry_Validate(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+
+	newPerson := func() *Entry {
+		e := NewEntry("uid=jdoe,ou=people,dc=example,dc=com")
+		e.AddAttributeValues("objectClass", []string{"top", "inetOrgPerson"})
+		e.AddAttributeValue("uid", "jdoe")
+		e.AddAttributeValue("commonName", "John Doe")
+		e.AddAttributeValue("sn", "Doe")
+		return e
+	}
+
+	tests := []struct {
+		name   string
+		modify func(e *Entry)
+		want   []SchemaViolation
+	}{
+		{
+			name:   "valid",
+			modify: func(e *Entry) {},
+		},
+		{
+			name:   "missing inherited attribute",
+			modify: func(e *Entry) { e.DeleteAttribute("sn") },
+			want:   []SchemaViolation{{Kind: MissingAttribute, Attribute: "sn", ObjectClass: "inetOrgPerson"}},
+		},
+		{
+			name:   "unknown object class",
+			modify: func(e *Entry) { e.AddAttributeValue("objectClass", "nonExistent") },
+			want:   []SchemaViolation{{Kind: UnknownObjectClass, ObjectClass: "nonExistent"}},
+		},
+		{
+			name:   "attribute not allowed",
+			modify: func(e *Entry) { e.AddAttributeValue("uidNumber", "1000") },
+			want:   []SchemaViolation{{Kind: NotAllowedAttribute, Attribute: "uidNumber"}},
+		},
+		{
+			name: "attribute allowed by auxiliary class",
+			modify: func(e *Entry) {
+				e.AddAttributeValue("objectClass", "extensibleObject")
+				e.AddAttributeValue("uidNumber", "1000")
+			},
+		},
+		{
+			name:   "unknown attribute",
+			modify: func(e *Entry) { e.AddAttributeValue("favouriteColour", "blue") },
+			want:   []SchemaViolation{{Kind: UnknownAttribute, Attribute: "favouriteColour"}},
+		},
+		{
+			name:   "single-valued attribute",
+			modify: func(e *Entry) { e.AddAttributeValues("displayName", []string{"John", "Johnny"}) },
+			want:   []SchemaViolation{{Kind: MultipleValues, Attribute: "displayName"}},
+		},
+		{
+			name:   "no user modification",
+			modify: func(e *Entry) { e.ReplaceAttributeValue("createTimestamp", "20240101000000Z") },
+			want:   []SchemaViolation{{Kind: NoUserModification, Attribute: "createTimestamp"}},
+		},
+		{
+			name: "missing attributes of auxiliary class",
+			modify: func(e *Entry) {
+				e.AddAttributeValue("objectClass", "posixAccount")
+				e.AddAttributeValue("uidNumber", "1000")
+			},
+			want: []SchemaViolation{
+				{Kind: MissingAttribute, Attribute: "gidNumber", ObjectClass: "posixAccount"},
+				{Kind: MissingAttribute, Attribute: "homeDirectory", ObjectClass: "posixAccount"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newPerson()
+			tt.modify(e)
+			assert.Equal(t, tt.want, e.Validate(schema))
+		})
+	}
+}
+
+func TestEntry_ValidateDelete(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+
+	e := NewEntry("cn=test,dc=example,dc=com")
+	e.ChangeType = ChangeDelete
+	assert.Nil(t, e.Validate(schema))
+}
+
+func TestEntry_ValidateUpdate(t *testing.T) {
+	schema := loadSchemaDump(t, "openldap.ldif")
+
+	// The entry was looked up with some of its attributes only.
+	partial := func(attrs map[string][]string) *Entry {
+		return NewEntryFromLdapEntry(ldap.NewEntry("uid=jdoe,ou=people,dc=example,dc=com", attrs))
+	}
+
+	tests := []struct {
+		name   string
+		entry  *Entry
+		modify func(e *Entry)
+		want   []SchemaViolation
+	}{
+		{
+			name:   "change without object classes",
+			entry:  partial(map[string][]string{"cn": {"John Doe"}, "mail": {"jdoe@example.com"}}),
+			modify: func(e *Entry) { e.ReplaceAttributeValue("cn", "Johnny") },
+		},
+		{
+			name:   "single-valued attribute without object classes",
+			entry:  partial(map[string][]string{"cn": {"John Doe"}}),
+			modify: func(e *Entry) { e.AddAttributeValues("displayName", []string{"John", "Johnny"}) },
+			want:   []SchemaViolation{{Kind: MultipleValues, Attribute: "displayName"}},
+		},
+		{
+			name:   "unknown attribute without object classes",
+			entry:  partial(map[string][]string{"cn": {"John Doe"}}),
+			modify: func(e *Entry) { e.AddAttributeValue("favouriteColour", "blue") },
+			want:   []SchemaViolation{{Kind: UnknownAttribute, Attribute: "favouriteColour"}},
+		},
+		{
+			name:   "required attributes that are not loaded",
+			entry:  partial(map[string][]string{"objectClass": {"top", "inetOrgPerson"}, "mail": {"jdoe@example.com"}}),
+			modify: func(e *Entry) { e.AddAttributeValue("mail", "john@example.com") },
+		},
+		{
+			name:   "attribute not allowed",
+			entry:  partial(map[string][]string{"objectClass": {"top", "inetOrgPerson"}}),
+			modify: func(e *Entry) { e.AddAttributeValue("uidNumber", "1000") },
+			want:   []SchemaViolation{{Kind: NotAllowedAttribute, Attribute: "uidNumber"}},
+		},
+		{
+			name:   "required attribute deleted",
+			entry:  partial(map[string][]string{"objectClass": {"top", "inetOrgPerson"}, "sn": {"Doe"}}),
+			modify: func(e *Entry) { e.DeleteAttribute("sn") },
+			want:   []SchemaViolation{{Kind: MissingAttribute, Attribute: "sn", ObjectClass: "inetOrgPerson"}},
+		},
+		{
+			name:  "object class added",
+			entry: partial(map[string][]string{"objectClass": {"top", "inetOrgPerson"}, "cn": {"John Doe"}, "sn": {"Doe"}}),
+			modify: func(e *Entry) {
+				e.AddAttributeValue("objectClass", "posixAccount")
+				e.AddAttributeValue("uidNumber", "1000")
+			},
+			want: []SchemaViolation{
+				{Kind: MissingAttribute, Attribute: "uid", ObjectClass: "posixAccount"},
+				{Kind: MissingAttribute, Attribute: "gidNumber", ObjectClass: "posixAccount"},
+				{Kind: MissingAttribute, Attribute: "homeDirectory", ObjectClass: "posixAccount"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.modify(tt.entry)
+			assert.Equal(t, tt.want, tt.entry.Validate(schema))
+		})
+	}
+}
+
+func TestEntry_UpdateWithSchemaValidation(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=subschema", map[string][]string{
+		"objectClass": {"subschema"},
+		"attributeTypes": {
+			"( 2.5.4.0 NAME 'objectClass' )",
+			"( 2.5.4.3 NAME 'cn' )",
+			"( 2.5.4.4 NAME 'sn' )",
+		},
+		"objectClasses": {
+			"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+			"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) )",
+		},
+	})
+
+	conn, err := Open(s.url(), WithSchemaValidation())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	e := NewEntry("cn=test,dc=example,dc=com")
+	e.AddAttributeValues("objectClass", []string{"top", "person"})
+	e.AddAttributeValue("cn", "test")
+
+	err = e.Update(conn)
+	assert.True(t, errors.Is(err, ErrConstraintViolation))
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, []SchemaViolation{{Kind: MissingAttribute, Attribute: "sn", ObjectClass: "person"}}, verr.Violations)
+	assert.Equal(t, int32(0), s.writes.Load())
+
+	// The entry can be fixed and sent after a failed validation.
+	e.AddAttributeValue("sn", "Test")
+	require.NoError(t, e.Update(conn))
+	assert.Equal(t, int32(1), s.writes.Load())
+}
+
+func TestEntry_UpdatePartialWithSchemaValidation(t *testing.T) {
+	s := newFakeServer(t)
+	s.put("cn=subschema", map[string][]string{
+		"objectClass": {"subschema"},
+		"attributeTypes": {
+			"( 2.5.4.0 NAME 'objectClass' )",
+			"( 2.5.4.3 NAME 'cn' )",
+			"( 2.5.4.4 NAME 'sn' )",
+			"( 2.5.4.13 NAME 'description' )",
+		},
+		"objectClasses": {
+			"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+			"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY description )",
+		},
+	})
+	s.put("cn=test,dc=example,dc=com", map[string][]string{"objectClass": {"top", "person"}, "cn": {"test"}, "sn": {"Test"}})
+
+	conn, err := Open(s.url(), WithSchemaValidation())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	e, err := conn.FindEntry("cn=test,dc=example,dc=com", "(objectClass=*)", []string{"description"})
+	require.NoError(t, err)
+	e.ReplaceAttributeValue("description", "updated")
+	require.NoError(t, e.Update(conn))
+	assert.Equal(t, []string{"updated"}, s.get("cn=test,dc=example,dc=com")["description"])
+}
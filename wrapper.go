@@ -7,6 +7,12 @@ func NewModifyRequest(dn string, controls []ldap.Control) *ldap.ModifyRequest {
 	return ldap.NewModifyRequest(dn, controls)
 }
 
+// NewModifyDNRequest creates a new ModifyDNRequest that renames the entry with
+// the given DN to the new RDN and, if newSuperior is not empty, moves it.
+func NewModifyDNRequest(dn string, newRDN string, deleteOldRDN bool, newSuperior string, controls []ldap.Control) *ldap.ModifyDNRequest {
+	return ldap.NewModifyDNWithControlsRequest(dn, newRDN, deleteOldRDN, newSuperior, controls)
+}
+
 // NewPasswordModifyRequest creates a new PasswordModifyRequest with the given user identity, old password and new password.
 func NewPasswordModifyRequest(userIdentity string, oldPassword string, newPassword string) *ldap.PasswordModifyRequest {
 	return ldap.NewPasswordModifyRequest(userIdentity, oldPassword, newPassword)